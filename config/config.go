@@ -33,11 +33,44 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+type JobConfig struct {
+	OverdueSweepInterval time.Duration `mapstructure:"overdue_sweep_interval"` // 逾期任务扫描间隔
+	TrashPurgeInterval   time.Duration `mapstructure:"trash_purge_interval"`   // 回收站清理间隔
+	TrashRetention       time.Duration `mapstructure:"trash_retention"`        // 回收站中任务的保留时长
+}
+
+type IdempotencyConfig struct {
+	TTL        time.Duration `mapstructure:"ttl"`         // 已完成请求的响应保留时长
+	PendingTTL time.Duration `mapstructure:"pending_ttl"` // 处理中请求的占位时长，超时后允许重新执行
+}
+
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"` // 例如 https://s3.amazonaws.com 或 http://minio:9000
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	PathStyle       bool   `mapstructure:"path_style"` // 使用 endpoint/bucket/key 形式的地址，MinIO 等兼容服务通常需要开启
+}
+
+type StorageConfig struct {
+	Driver       string        `mapstructure:"driver"`        // 附件存储方式：local（本地文件系统）或 s3
+	LocalDir     string        `mapstructure:"local_dir"`     // 本地存储的根目录
+	MaxFileSize  int64         `mapstructure:"max_file_size"` // 单个附件的最大字节数
+	UserQuota    int64         `mapstructure:"user_quota"`    // 每个用户附件总大小的上限（字节）
+	URLTTL       time.Duration `mapstructure:"url_ttl"`       // 附件下载链接的有效期
+	AllowedTypes []string      `mapstructure:"allowed_types"` // 允许上传的文件类型，以 / 结尾表示该大类下的全部类型
+	S3           S3Config      `mapstructure:"s3"`
+}
+
 type AppConfig struct {
-	Env      string         `mapstructure:"env"`
-	Database DatabaseConfig `mapstructure:"database"`
-	SMS      SMSConfig      `mapstructure:"sms"`
-	Redis    RedisConfig    `mapstructure:"redis"`
+	Env         string            `mapstructure:"env"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	SMS         SMSConfig         `mapstructure:"sms"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Job         JobConfig         `mapstructure:"job"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Storage     StorageConfig     `mapstructure:"storage"`
 }
 
 var Cfg *AppConfig
@@ -99,6 +132,39 @@ func LoadConfig(path string) error {
 	if Cfg.Database.SSLMode == "" {
 		Cfg.Database.SSLMode = "disable" // 数据库 SSL 模式默认为禁用
 	}
+	if Cfg.Job.OverdueSweepInterval == 0 {
+		Cfg.Job.OverdueSweepInterval = 5 * time.Minute // 逾期任务默认每 5 分钟扫描一次
+	}
+	if Cfg.Job.TrashPurgeInterval == 0 {
+		Cfg.Job.TrashPurgeInterval = time.Hour // 回收站默认每小时清理一次
+	}
+	if Cfg.Job.TrashRetention == 0 {
+		Cfg.Job.TrashRetention = 30 * 24 * time.Hour // 回收站中的任务默认保留 30 天
+	}
+	if Cfg.Idempotency.TTL == 0 {
+		Cfg.Idempotency.TTL = 24 * time.Hour // 幂等响应默认保留 24 小时
+	}
+	if Cfg.Idempotency.PendingTTL == 0 {
+		Cfg.Idempotency.PendingTTL = 2 * time.Minute
+	}
+	if Cfg.Storage.Driver == "" {
+		Cfg.Storage.Driver = "local"
+	}
+	if Cfg.Storage.LocalDir == "" {
+		Cfg.Storage.LocalDir = "./data/attachments"
+	}
+	if Cfg.Storage.MaxFileSize == 0 {
+		Cfg.Storage.MaxFileSize = 20 << 20 // 单个附件默认最大 20MB
+	}
+	if Cfg.Storage.UserQuota == 0 {
+		Cfg.Storage.UserQuota = 500 << 20 // 每个用户默认 500MB
+	}
+	if Cfg.Storage.URLTTL == 0 {
+		Cfg.Storage.URLTTL = 15 * time.Minute
+	}
+	if len(Cfg.Storage.AllowedTypes) == 0 {
+		Cfg.Storage.AllowedTypes = []string{"image/", "application/pdf", "text/plain", "application/zip", "audio/", "video/"}
+	}
 	return nil
 }
 
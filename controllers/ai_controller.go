@@ -23,7 +23,9 @@ func AIAssistant(c *gin.Context) {
 		return
 	}
 
-	response, err := ai_service.ProcessTaskWithAI(uid, req.Input)
+	response, batch, err := ai_service.ProcessTaskWithAI(uid, req.Input)
+	// 向幂等中间件上报工具调用是否已提交，已提交的请求重试时不会再次执行
+	util.SetIdempotencyCommit(c, batch.Committed, batch.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -114,7 +116,9 @@ func setStreamHeaders(c *gin.Context) {
 }
 
 // writeStreamError writes an error message to the stream
+// 出错的流只输出了部分内容，标记为未完整写出，避免被当作成功响应保存和重放
 func writeStreamError(c *gin.Context, err error) {
+	util.MarkResponseIncomplete(c)
 	errorMsg := fmt.Sprintf("data: {\"error\": \"%s\"}\n\n", err.Error())
 	c.Writer.Write([]byte(errorMsg))
 	c.Writer.Flush()
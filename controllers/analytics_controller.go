@@ -4,7 +4,9 @@ package controllers
 import (
 	"AITodo/dto"
 	"AITodo/services"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +20,8 @@ import (
 // @Param start query string true "开始时间 (格式: 2006-01-02)"
 // @Param end query string true "结束时间 (格式: 2006-01-02)"
 // @Param interval query string true "时间间隔 (week/month/year)"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
 // @Security ApiKeyAuth
 // @Success 200 {object} TrendResponse
 // @Router /analytics/trend [get]
@@ -26,6 +30,14 @@ func GetTrendHandler(c *gin.Context) {
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 	interval := c.Query("interval")
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
 
 	// 解析时间
 	start, err := time.Parse("2006-01-02", startStr)
@@ -41,7 +53,7 @@ func GetTrendHandler(c *gin.Context) {
 	}
 
 	// 获取趋势数据
-	data, err := services.GetTrendData(userID, interval, start, end)
+	data, err := services.GetTrendData(userID, interval, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -59,6 +71,8 @@ func GetTrendHandler(c *gin.Context) {
 // @Param start query string true "开始时间 (格式: 2006-01-02)"
 // @Param end query string true "结束时间 (格式: 2006-01-02)"
 // @Param interval query string true "时间间隔 (week/month/year)"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
 // @Security ApiKeyAuth
 // @Success 200 {object} CategoryDistributionResponse
 // @Router /analytics/category_distribution [get]
@@ -67,6 +81,14 @@ func GetCategoryDistributionHandler(c *gin.Context) {
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 	interval := c.Query("interval")
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
 
 	// 解析时间
 	start, err := time.Parse("2006-01-02", startStr)
@@ -82,53 +104,70 @@ func GetCategoryDistributionHandler(c *gin.Context) {
 	}
 
 	// 获取分类分布数据
-	data, err := services.GetCategoryDistribution(userID, interval, start, end)
+	data, err := services.GetCategoryDistribution(userID, interval, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	response := dto.CategoryDistributionResponse{
-		Categories: []dto.CategoryData{
-			{
-				Name:  "工作",
-				Value: data.Work,
-				Color: "#4CAF50",
-			},
-			{
-				Name:  "学习",
-				Value: data.Study,
-				Color: "#2196F3",
-			},
-			{
-				Name:  "生活",
-				Value: data.Life,
-				Color: "#FFC107",
-			},
-			{
-				Name:  "健身",
-				Value: data.Fitness,
-				Color: "#F44336",
-			},
-			{
-				Name:  "其他",
-				Value: data.Other,
-				Color: "#9E9E9E",
-			},
-		},
+	c.JSON(http.StatusOK, data)
+}
+
+// GetTagDistributionHandler 按标签统计任务
+// @Summary 获取标签分布
+// @Description 获取每个标签的任务数量及各状态数量
+// @Tags 数据分析
+// @Produce json
+// @Param start query string true "开始时间 (格式: 2006-01-02)"
+// @Param end query string true "结束时间 (格式: 2006-01-02)"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
+// @Security ApiKeyAuth
+// @Success 200 {object} TagDistributionResponse
+// @Router /analytics/tag_distribution [get]
+func GetTagDistributionHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	// 解析时间
+	start, err := time.Parse("2006-01-02", c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date format"})
+		return
+	}
+
+	end, err := time.Parse("2006-01-02", c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date format"})
+		return
+	}
+
+	data, err := services.GetTagDistribution(userID, level, projectID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TagDistributionResponse{Tags: data})
 }
 
 // GetHeatmapHandler 获取用户活跃时段分析
 // @Summary 获取用户活跃时段分析
-// @Description 获取热力图所需数据
+// @Description 获取热力图所需数据，focus_slots 为番茄钟专注时长按小时的分布
 // @Tags 数据分析
 // @Produce json
 // @Param start query string true "开始时间 (格式: 2006-01-02)"
 // @Param end query string true "结束时间 (格式: 2006-01-02)"
 // @Param interval query string true "时间间隔 (week/month/year)"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
 // @Security ApiKeyAuth
 // @Success 200 {object} HeatmapResponse
 // @Router /analytics/heatmap [get]
@@ -138,6 +177,14 @@ func GetHeatmapHandler(c *gin.Context) {
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 	interval := c.Query("interval")
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
 
 	// 解析时间
 	start, err := time.Parse("2006-01-02", startStr)
@@ -153,14 +200,21 @@ func GetHeatmapHandler(c *gin.Context) {
 	}
 
 	// 获取活跃时段数据
-	data, err := services.GetHeatmapData(userID, interval, start, end)
+	data, err := services.GetHeatmapData(userID, interval, level, projectID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	focusData, err := services.GetFocusHeatmap(userID, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := dto.HeatmapResponse{
-		TimeSlots: data,
+		TimeSlots:  data,
+		FocusSlots: focusData,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -174,6 +228,8 @@ func GetHeatmapHandler(c *gin.Context) {
 // @Param start query string true "开始时间 (格式: 2006-01-02)"
 // @Param end query string true "结束时间 (格式: 2006-01-02)"
 // @Param interval query string true "时间间隔 (week/month/year)"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
 // @Security ApiKeyAuth
 // @Success 200 {object} CombinedResponse
 // @Router /analytics/combined [get]
@@ -182,6 +238,14 @@ func GetCombinedDataHandler(c *gin.Context) {
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 	interval := c.Query("interval")
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
 
 	// 解析时间
 	start, err := time.Parse("2006-01-02", startStr)
@@ -197,7 +261,7 @@ func GetCombinedDataHandler(c *gin.Context) {
 	}
 
 	// 获取趋势图数据
-	trendData, err := services.GetTrendData(userID, interval, start, end)
+	trendData, err := services.GetTrendData(userID, interval, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -205,61 +269,162 @@ func GetCombinedDataHandler(c *gin.Context) {
 	trendResponse := formatTrendResponse(interval, trendData)
 
 	// 获取分类分布图数据
-	categoryData, err := services.GetCategoryDistribution(userID, interval, start, end)
+	categoryData, err := services.GetCategoryDistribution(userID, interval, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	categoryResponse := dto.CategoryDistributionResponse{
-		Categories: []dto.CategoryData{
-			{
-				Name:  "工作",
-				Value: categoryData.Work,
-				Color: "#4CAF50",
-			},
-			{
-				Name:  "学习",
-				Value: categoryData.Study,
-				Color: "#2196F3",
-			},
-			{
-				Name:  "生活",
-				Value: categoryData.Life,
-				Color: "#FFC107",
-			},
-			{
-				Name:  "健身",
-				Value: categoryData.Fitness,
-				Color: "#F44336",
-			},
-			{
-				Name:  "其他",
-				Value: categoryData.Other,
-				Color: "#9E9E9E",
-			},
-		},
-	}
 
 	// 获取热力图数据
-	heatmapData, err := services.GetHeatmapData(userID, interval, start, end)
+	heatmapData, err := services.GetHeatmapData(userID, interval, level, projectID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	focusData, err := services.GetFocusHeatmap(userID, level, projectID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	heatmapResponse := dto.HeatmapResponse{
-		TimeSlots: heatmapData,
+		TimeSlots:  heatmapData,
+		FocusSlots: focusData,
 	}
 
 	// 合并数据并返回
 	response := dto.CombinedResponse{
 		Trend:                trendResponse,
-		CategoryDistribution: categoryResponse,
+		CategoryDistribution: *categoryData,
 		Heatmap:              heatmapResponse,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetTimeTrackingHandler 对比预计用时与实际计时
+// @Summary 预计与实际用时对比
+// @Description 按类别和时间段汇总任务的预计用时与计时记录
+// @Tags 数据分析
+// @Produce json
+// @Param start query string true "开始时间 (格式: 2006-01-02)"
+// @Param end query string true "结束时间 (格式: 2006-01-02)"
+// @Param interval query string false "时间间隔 (day/week/month/year)，默认day"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
+// @Security ApiKeyAuth
+// @Success 200 {object} TimeTrackingResponse
+// @Router /analytics/time_tracking [get]
+func GetTimeTrackingHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
+
+	// 计时记录以本地时间划分日期
+	start, err := time.ParseInLocation("2006-01-02", c.Query("start"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date format"})
+		return
+	}
+
+	end, err := time.ParseInLocation("2006-01-02", c.Query("end"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date format"})
+		return
+	}
+
+	data, err := services.GetTimeTracking(userID, c.Query("interval"), level, projectID, start, end)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAnalyticsRange) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetFocusHandler 获取番茄钟专注统计
+// @Summary 番茄钟专注统计
+// @Description 按时间段统计专注时长、完成的番茄钟和打断次数，并附带专注时长按小时的分布
+// @Tags 数据分析
+// @Produce json
+// @Param start query string true "开始时间 (格式: 2006-01-02)"
+// @Param end query string true "结束时间 (格式: 2006-01-02)"
+// @Param interval query string false "时间间隔 (day/week/month/year)，默认day"
+// @Param level query string false "统计层级 (all/leaf/top)，默认all"
+// @Param project query int false "项目ID，不传表示全部项目"
+// @Security ApiKeyAuth
+// @Success 200 {object} FocusResponse
+// @Router /analytics/focus [get]
+func GetFocusHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	level, ok := parseAnalyticsLevel(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseAnalyticsProject(c)
+	if !ok {
+		return
+	}
+
+	// 专注时间以本地时间划分日期
+	start, err := time.ParseInLocation("2006-01-02", c.Query("start"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date format"})
+		return
+	}
+
+	end, err := time.ParseInLocation("2006-01-02", c.Query("end"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date format"})
+		return
+	}
+
+	data, err := services.GetFocusStats(userID, c.Query("interval"), level, projectID, start, end)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAnalyticsRange) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// parseAnalyticsLevel 解析统计接口的 level 参数，未传时返回 all
+func parseAnalyticsLevel(c *gin.Context) (string, bool) {
+	level := c.DefaultQuery("level", services.TaskLevelAll)
+	if !services.IsValidTaskLevel(level) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid level"})
+		return "", false
+	}
+	return level, true
+}
+
+// parseAnalyticsProject 解析统计接口的 project 参数，未传时返回 0
+func parseAnalyticsProject(c *gin.Context) (uint, bool) {
+	raw := c.Query("project")
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
 func formatTrendResponse(interval string, data *dto.TrendData) dto.TrendResponse {
 	return dto.TrendResponse{
 		TimeRange: interval,
@@ -275,6 +440,11 @@ func formatTrendResponse(interval string, data *dto.TrendData) dto.TrendResponse
 				Data:  data.Pending,
 				Color: "#FFC107",
 			},
+			{
+				Name:  "In Progress",
+				Data:  data.Progress,
+				Color: "#2196F3",
+			},
 			{
 				Name:  "Failed",
 				Data:  data.Failed,
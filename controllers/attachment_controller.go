@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"AITodo/config"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead 上传请求中表单字段和分隔符允许占用的额外字节数
+const multipartOverhead = 1 << 20
+
+// GetAttachments 获取任务的附件及其临时下载地址
+func GetAttachments(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := services.ListAttachments(uid, id)
+	if err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": attachments})
+}
+
+// UploadAttachment 为任务上传附件，使用 multipart/form-data 的 file 字段
+func UploadAttachment(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	// 限制请求体大小，避免超大文件在解析表单时占满磁盘
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Cfg.Storage.MaxFileSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少上传文件:" + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := services.UploadAttachment(uid, id, header.Filename, file, header.Size)
+	if err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": attachment})
+}
+
+// DeleteAttachment 删除任务的附件
+func DeleteAttachment(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的附件ID"})
+		return
+	}
+
+	if err := services.DeleteAttachment(uid, id, uint(attachmentID)); err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// DownloadAttachment 通过带签名的临时地址下载附件，无需携带认证令牌
+func DownloadAttachment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的附件ID"})
+		return
+	}
+
+	attachment, content, err := services.OpenAttachment(uint(id), c.Query("expires"), c.Query("sig"))
+	if err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	// 图片和 PDF 可以直接在浏览器中预览，其他类型一律作为下载处理
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") || attachment.ContentType == "application/pdf" {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=300",
+	})
+}
+
+// GetAttachmentUsage 获取当前用户的附件用量和配额
+func GetAttachmentUsage(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	usage, err := services.GetAttachmentUsage(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": usage})
+}
+
+// attachmentErrorStatus 将附件服务的错误转换为 HTTP 状态码
+func attachmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrAttachmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, services.ErrInvalidDownloadURL):
+		return http.StatusForbidden
+	}
+	return taskErrorStatus(err)
+}
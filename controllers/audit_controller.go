@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskHistory 获取任务的修改记录，包含操作者和修改前后的字段差异
+func GetTaskHistory(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	audits, err := services.GetTaskHistory(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": audits})
+}
+
+// UndoTask 撤销任务最近一次修改
+func UndoTask(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	audit, err := services.UndoTaskChange(uid, id, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": audit})
+}
+
+// UndoLastAIAction 撤销 AI 助手最近一次会话做出的全部修改
+func UndoLastAIAction(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	audits, err := services.UndoLastAIBatch(uid, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": audits})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBoard 获取按状态分列的看板，支持与任务列表相同的筛选参数，limit 为每列的最大任务数
+func GetBoard(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.TaskQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns, err := services.GetBoard(uid, query)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": columns})
+}
+
+// MoveOnBoard 在看板中拖动任务，可以同时修改状态（所在列）和列内位置
+func MoveOnBoard(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.BoardMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if version == 0 {
+		version = req.Version
+	}
+
+	task, err := services.MoveOnBoard(uid, id, req, services.WriteOptions{Version: version, Force: req.Force, Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkTasks 在同一事务中批量创建、修改、删除任务或修改状态
+func BulkTasks(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	outcomes, committed, err := services.BulkApply(uid, req.Mode, req.Operations, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := dto.BulkTaskResponse{Mode: req.Mode, Committed: committed, Results: make([]dto.BulkResult, len(outcomes))}
+	if resp.Mode == "" {
+		resp.Mode = services.BulkAtomic
+	}
+	for i, o := range outcomes {
+		result := dto.BulkResult{Index: o.Index, Op: o.Op, ID: o.ID, Task: o.Task, Status: http.StatusOK}
+		if o.Op == services.BulkCreate {
+			result.Status = http.StatusCreated
+		}
+		if o.Err != nil {
+			result.Status, result.Error = taskErrorStatus(o.Err), o.Err.Error()
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results[i] = result
+	}
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCalendar 获取日历视图中的任务排布
+// GET /calendar?view=week&date=2026-11-05&tz=Asia/Shanghai，view 可选 day/week/month，默认 week；date 默认今天
+// tz 为 IANA 时区名，决定日期的划分和全天任务的判断，默认使用服务器所在时区
+func GetCalendar(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := time.Local
+	if tz := c.Query("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时区:" + tz})
+			return
+		}
+	}
+
+	date, err := services.ParseQueryTimeIn("date", c.Query("date"), false, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if date == nil {
+		now := time.Now()
+		date = &now
+	}
+
+	calendar, err := services.GetCalendar(uid, c.Query("view"), *date, loc)
+	if err != nil {
+		status := taskErrorStatus(err)
+		if errors.Is(err, services.ErrInvalidCalendarView) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": calendar})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategories 获取当前用户的类别
+func GetCategories(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories, err := services.ListCategories(uid)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": categories})
+}
+
+// CreateCategory 创建类别
+func CreateCategory(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := services.CreateCategory(uid, req)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": category})
+}
+
+// UpdateCategory 修改类别名称或颜色，重命名时任务随之改名
+func UpdateCategory(c *gin.Context) {
+	uid, id, ok := parseCategoryParams(c)
+	if !ok {
+		return
+	}
+
+	var patch dto.CategoryPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := services.UpdateCategory(uid, id, patch)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+// DeleteCategory 删除类别，其中的任务归入默认类别
+func DeleteCategory(c *gin.Context) {
+	uid, id, ok := parseCategoryParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteCategory(uid, id); err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// parseCategoryParams 解析当前用户和路径中的类别ID
+func parseCategoryParams(c *gin.Context) (uint, uint, bool) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的类别ID"})
+		return 0, 0, false
+	}
+	return uid, uint(id), true
+}
+
+// categoryErrorStatus 将类别相关错误映射为 HTTP 状态码
+func categoryErrorStatus(err error) int {
+	if errors.Is(err, models.ErrCategoryNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrCategoryExists) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, services.ErrInvalidCategory) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChecklist 获取任务的检查项及完成进度
+func GetChecklist(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	checklist, err := services.GetChecklist(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setChecklistETag(c, checklist)
+	c.JSON(http.StatusOK, gin.H{"data": checklist})
+}
+
+// AddChecklistItem 在任务末尾添加检查项
+func AddChecklistItem(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts, ok := checklistWriteOptions(c, uid)
+	if !ok {
+		return
+	}
+
+	checklist, err := services.AddChecklistItem(uid, id, req.Content, opts)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setChecklistETag(c, checklist)
+	c.JSON(http.StatusCreated, gin.H{"data": checklist})
+}
+
+// UpdateChecklistItem 修改检查项内容或勾选/取消勾选检查项
+func UpdateChecklistItem(c *gin.Context) {
+	uid, id, itemID, ok := parseChecklistItemParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ChecklistItemPatch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts, ok := checklistWriteOptions(c, uid)
+	if !ok {
+		return
+	}
+
+	checklist, err := services.UpdateChecklistItem(uid, id, itemID, req, opts)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setChecklistETag(c, checklist)
+	c.JSON(http.StatusOK, gin.H{"data": checklist})
+}
+
+// ReorderChecklist 调整检查项顺序
+func ReorderChecklist(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ReorderChecklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts, ok := checklistWriteOptions(c, uid)
+	if !ok {
+		return
+	}
+
+	checklist, err := services.ReorderChecklist(uid, id, req.IDs, opts)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setChecklistETag(c, checklist)
+	c.JSON(http.StatusOK, gin.H{"data": checklist})
+}
+
+// DeleteChecklistItem 删除检查项
+func DeleteChecklistItem(c *gin.Context) {
+	uid, id, itemID, ok := parseChecklistItemParams(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := checklistWriteOptions(c, uid)
+	if !ok {
+		return
+	}
+
+	checklist, err := services.DeleteChecklistItem(uid, id, itemID, opts)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setChecklistETag(c, checklist)
+	c.JSON(http.StatusOK, gin.H{"data": checklist})
+}
+
+// checklistWriteOptions 根据 If-Match 请求头构造检查项的写入选项
+func checklistWriteOptions(c *gin.Context, uid uint) (services.WriteOptions, bool) {
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return services.WriteOptions{}, false
+	}
+	return services.WriteOptions{Version: version, Actor: models.UserActor(uid)}, true
+}
+
+// setChecklistETag 以任务的版本号设置 ETag，检查项的修改会使任务版本号加一
+func setChecklistETag(c *gin.Context, checklist *dto.TaskChecklist) {
+	c.Header("ETag", fmt.Sprintf("\"%d\"", checklist.Version))
+}
+
+func parseChecklistItemParams(c *gin.Context) (uint, uint, uint, bool) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的检查项ID"})
+		return 0, 0, 0, false
+	}
+	return uid, id, uint(itemID), true
+}
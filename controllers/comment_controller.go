@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetComments 获取任务的评论
+func GetComments(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	comments, err := services.ListComments(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": comments})
+}
+
+// CreateComment 为任务添加评论
+func CreateComment(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := services.CreateComment(uid, id, req.Body)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+// UpdateComment 修改评论内容
+func UpdateComment(c *gin.Context) {
+	uid, id, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := services.UpdateComment(uid, id, commentID, req.Body)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// DeleteComment 删除评论
+func DeleteComment(c *gin.Context) {
+	uid, id, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteComment(uid, id, commentID); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetTaskActivity 获取任务的动态，评论与状态变化按时间顺序排列
+func GetTaskActivity(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	activities, err := services.GetTaskActivity(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": activities})
+}
+
+func parseCommentParams(c *gin.Context) (uint, uint, uint, bool) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的评论ID"})
+		return 0, 0, 0, false
+	}
+	return uid, id, uint(commentID), true
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/services"
+	"AITodo/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetDependencies 获取任务的前置任务和后续任务
+func GetDependencies(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	deps, err := services.GetDependencies(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deps})
+}
+
+// AddDependency 为任务添加前置任务
+func AddDependency(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AddDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dep, err := services.AddDependency(uid, id, req.BlockedByID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": dep})
+}
+
+// RemoveDependency 删除任务的前置任务
+func RemoveDependency(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	blockerID, err := strconv.ParseUint(c.Param("blocker_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	if err := services.RemoveDependency(uid, id, uint(blockerID)); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetActionableTasks 获取所有前置任务均已完成、当前可以开始的任务
+func GetActionableTasks(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := services.GetActionableTasks(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tasks})
+}
+
+// GetCriticalPath 计算依赖图的关键路径，task_id 可选，指定时计算到该任务为止的路径
+func GetCriticalPath(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target uint64
+	if raw := c.Query("task_id"); raw != "" {
+		if target, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+			return
+		}
+	}
+
+	resp, err := services.GetCriticalPath(uid, uint(target))
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
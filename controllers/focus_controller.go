@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartFocusSession 为任务开始番茄钟会话，请求体可省略
+func StartFocusSession(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.FocusStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := services.StartFocusSession(uid, id, req)
+	if err != nil {
+		c.JSON(focusErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": session})
+}
+
+// GetFocusSessions 获取任务的番茄钟会话
+func GetFocusSessions(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := services.ListFocusSessions(uid, id)
+	if err != nil {
+		c.JSON(focusErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// GetCurrentFocusSession 获取当前用户进行中的番茄钟会话，没有时 data 为 null
+func GetCurrentFocusSession(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := services.GetCurrentFocusSession(uid)
+	if err != nil {
+		c.JSON(focusErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+// PauseFocusSession 暂停当前番茄钟
+func PauseFocusSession(c *gin.Context) {
+	updateFocusSession(c, services.PauseFocusSession)
+}
+
+// ResumeFocusSession 继续已暂停的番茄钟
+func ResumeFocusSession(c *gin.Context) {
+	updateFocusSession(c, services.ResumeFocusSession)
+}
+
+// InterruptFocusSession 记录一次打断
+func InterruptFocusSession(c *gin.Context) {
+	updateFocusSession(c, services.InterruptFocusSession)
+}
+
+// NextFocusPomodoro 跳过休息，开始下一个番茄钟
+func NextFocusPomodoro(c *gin.Context) {
+	updateFocusSession(c, services.NextFocusPomodoro)
+}
+
+// StopFocusSession 结束当前番茄钟会话
+func StopFocusSession(c *gin.Context) {
+	updateFocusSession(c, services.StopFocusSession)
+}
+
+// updateFocusSession 对当前用户进行中的会话执行操作并返回最新状态
+func updateFocusSession(c *gin.Context, action func(uint) (*models.FocusSession, error)) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := action(uid)
+	if err != nil {
+		c.JSON(focusErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+// focusErrorStatus 将番茄钟服务的错误转换为 HTTP 状态码
+func focusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrNoActiveFocusSession):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFocusSessionActive), errors.Is(err, services.ErrInvalidFocusAction):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidFocusSettings):
+		return http.StatusBadRequest
+	}
+	return taskErrorStatus(err)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProjects 获取当前用户的项目，include_archived=true 时包含已归档的项目
+func GetProjects(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	projects, err := services.ListProjects(uid, c.Query("include_archived") == "true")
+	if err != nil {
+		c.JSON(projectErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": projects})
+}
+
+// CreateProject 创建项目
+func CreateProject(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := services.CreateProject(uid, req)
+	if err != nil {
+		c.JSON(projectErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": project})
+}
+
+// UpdateProject 修改项目名称、颜色、图标或归档状态
+func UpdateProject(c *gin.Context) {
+	uid, id, ok := parseProjectParams(c)
+	if !ok {
+		return
+	}
+
+	var patch dto.ProjectPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := services.UpdateProject(uid, id, patch)
+	if err != nil {
+		c.JSON(projectErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": project})
+}
+
+// ReorderProjects 调整项目的显示顺序
+func ReorderProjects(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.ReorderProjectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ReorderProjects(uid, req.IDs); err != nil {
+		status := projectErrorStatus(err)
+		// ids 与用户的项目不一致属于请求错误
+		if errors.Is(err, models.ErrProjectNotFound) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// DeleteProject 删除项目，项目中的任务保留并移出项目
+func DeleteProject(c *gin.Context) {
+	uid, id, ok := parseProjectParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteProject(uid, id, services.WriteOptions{Actor: models.UserActor(uid)}); err != nil {
+		c.JSON(projectErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// parseProjectParams 解析当前用户和路径中的项目ID
+func parseProjectParams(c *gin.Context) (uint, uint, bool) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的项目ID"})
+		return 0, 0, false
+	}
+	return uid, uint(id), true
+}
+
+// projectErrorStatus 将项目相关错误映射为 HTTP 状态码
+func projectErrorStatus(err error) int {
+	if errors.Is(err, models.ErrProjectNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrInvalidProject) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, services.ErrProjectExists) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetOccurrences 获取时间窗口内的全部任务发生，重复任务会被展开
+// GET /task/occurrences?start=2026-11-01&end=2026-11-30
+func GetOccurrences(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	occurrences, err := services.ExpandOccurrences(uid, start, end)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": occurrences})
+}
+
+// GetTaskOccurrences 获取单个重复任务在时间窗口内的发生
+// GET /task/:id/occurrences?start=2026-11-01&end=2026-11-30
+func GetTaskOccurrences(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	start, end, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	occurrences, err := services.GetSeriesOccurrences(uid, id, start, end)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": occurrences})
+}
+
+// UpdateOccurrence 编辑重复任务的一次发生
+// PATCH /task/:id/occurrences?at=<occurrence_at>&scope=this|following|all，仍有未完成的前置任务时可用 force=true 强制完成
+func UpdateOccurrence(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	at, err := parseOccurrenceAt(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var patch dto.TaskPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := services.UpdateOccurrence(uid, id, at, c.Query("scope"), patch, services.WriteOptions{Version: version, Force: c.Query("force") == "true", Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
+
+// CompleteOccurrence 完成重复任务的一次发生
+// POST /task/:id/occurrences/complete?at=<occurrence_at>，仍有未完成的前置任务时可用 force=true 强制完成
+func CompleteOccurrence(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	at, err := parseOccurrenceAt(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := services.CompleteOccurrence(uid, id, at, services.WriteOptions{Version: version, Force: c.Query("force") == "true", Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
+
+// DeleteOccurrence 删除重复任务的一次发生
+// DELETE /task/:id/occurrences?at=<occurrence_at>&scope=this|following|all
+func DeleteOccurrence(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	at, err := parseOccurrenceAt(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.DeleteOccurrence(uid, id, at, c.Query("scope"), services.WriteOptions{Actor: models.UserActor(uid)}); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// parseTaskParams 解析当前用户和路径中的任务ID，失败时直接写入响应
+func parseTaskParams(c *gin.Context) (uint, uint, bool) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
+		return 0, 0, false
+	}
+	return uid, uint(id), true
+}
+
+// parseWindow 解析 start/end 查询参数
+func parseWindow(c *gin.Context) (time.Time, time.Time, error) {
+	start, err := services.ParseQueryTime("start", c.Query("start"), false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := services.ParseQueryTime("end", c.Query("end"), true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if start == nil || end == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start和end为必填参数")
+	}
+	return *start, *end, nil
+}
+
+func parseOccurrenceAt(c *gin.Context) (time.Time, error) {
+	at, err := services.ParseQueryTime("at", c.Query("at"), false)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if at == nil {
+		return time.Time{}, fmt.Errorf("at为必填参数")
+	}
+	return *at, nil
+}
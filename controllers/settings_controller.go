@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOverduePolicy 获取当前用户的逾期处理策略
+func GetOverduePolicy(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := services.GetOverduePolicy(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dto.OverduePolicyRequest{Policy: policy}})
+}
+
+// UpdateOverduePolicy 修改当前用户的逾期处理策略
+func UpdateOverduePolicy(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.OverduePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetOverduePolicy(uid, req.Policy); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidOverduePolicy) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": req})
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTags 获取当前用户的标签及使用次数
+func GetTags(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, err := services.ListTags(uid)
+	if err != nil {
+		c.JSON(tagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// CreateTag 创建标签
+func CreateTag(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := services.CreateTag(uid, req.Name)
+	if err != nil {
+		c.JSON(tagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": tag})
+}
+
+// RenameTag 重命名标签，新名称已存在时返回 409，需要改用合并接口
+func RenameTag(c *gin.Context) {
+	uid, id, ok := parseTagParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := services.RenameTag(uid, id, req.Name)
+	if err != nil {
+		c.JSON(tagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+// MergeTag 将标签合并到另一个标签
+func MergeTag(c *gin.Context) {
+	uid, id, ok := parseTagParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := services.MergeTag(uid, id, req.IntoID)
+	if err != nil {
+		c.JSON(tagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+// DeleteTag 删除标签，并从所有任务上移除
+func DeleteTag(c *gin.Context) {
+	uid, id, ok := parseTagParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteTag(uid, id); err != nil {
+		c.JSON(tagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// parseTagParams 解析当前用户和路径中的标签ID
+func parseTagParams(c *gin.Context) (uint, uint, bool) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的标签ID"})
+		return 0, 0, false
+	}
+	return uid, uint(id), true
+}
+
+// tagErrorStatus 将标签相关错误映射为 HTTP 状态码
+func tagErrorStatus(err error) int {
+	if errors.Is(err, models.ErrTagNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrTagExists) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, services.ErrInvalidTag) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
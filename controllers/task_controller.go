@@ -1,22 +1,62 @@
 package controllers
 
 import (
+	"AITodo/dto"
 	"AITodo/models"
 	"AITodo/services"
 	"AITodo/util"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// GetAllTasks 获取所有任务
+// GetAllTasks 分页获取当前用户的任务，支持筛选和多字段排序
 func GetAllTasks(c *gin.Context) {
-	tasks, err := services.GetAllTasks()
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.TaskQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := services.ListTasks(uid, query)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetTask 获取单个任务
+func GetTask(c *gin.Context) {
+	uid, err := util.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	task, err := services.GetTask(uid, uint(id))
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": tasks})
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, gin.H{"data": task})
 }
 
 // CreateTask 创建新任务
@@ -34,8 +74,8 @@ func CreateTask(c *gin.Context) {
 	}
 	task.UserID = uid
 
-	if err := services.CreateTask(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"创建任务失败": err.Error()})
+	if err := services.CreateTask(task, services.WriteOptions{Actor: models.UserActor(uid)}); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"创建任务失败": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"data": "创建成功"})
@@ -43,6 +83,12 @@ func CreateTask(c *gin.Context) {
 
 // UpdateTask 更新任务
 func UpdateTask(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
@@ -55,26 +101,198 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := services.UpdateTask(uint(id), req)
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := services.UpdateTask(uid, uint(id), req, services.WriteOptions{Version: version, Force: c.Query("force") == "true", Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
+
+// PatchTask 部分更新任务，只修改请求体中出现的字段
+func PatchTask(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var patch dto.TaskPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := services.PatchTask(uid, uint(id), patch, services.WriteOptions{Version: version, Force: c.Query("force") == "true", Actor: models.UserActor(uid)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	setTaskETag(c, task)
 	c.JSON(http.StatusOK, gin.H{"data": task})
 }
 
 // DeleteTask 删除任务
 func DeleteTask(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "无效的任务ID"})
 		return
 	}
 
-	if err := services.DeleteTask(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// children=cascade（默认）删除整棵子树，children=promote 将子任务提升一级
+	if err := services.DeleteTaskWithChildren(uid, uint(id), c.Query("children"), services.WriteOptions{Actor: models.UserActor(uid)}); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// GetTaskTree 获取任务及其子任务树，包含汇总的完成进度
+func GetTaskTree(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	tree, err := services.GetTaskTree(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tree})
+}
+
+// MoveTask 将任务连同子树移动到新的父任务下
+func MoveTask(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := services.MoveTask(uid, id, req.ParentID, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
+
+// GetStatusHistory 获取任务的状态流转记录
+func GetStatusHistory(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	histories, err := services.GetStatusHistory(uid, id)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": histories})
+}
+
+// GetEisenhowerMatrix 按重要和紧急程度将未完成的任务划分到四象限
+// urgent_within 指定截止时间在多久之内视为紧急，例如 24h，默认 48h
+func GetEisenhowerMatrix(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	within := services.DefaultUrgentWithin
+	if raw := c.Query("urgent_within"); raw != "" {
+		within, err = time.ParseDuration(raw)
+		if err != nil || within <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的urgent_within"})
+			return
+		}
+	}
+
+	matrix, err := services.GetEisenhowerMatrix(uid, time.Now(), within)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": matrix})
+}
+
+// taskErrorStatus 将任务相关错误映射为 HTTP 状态码
+// 访问其他用户的任务与任务不存在一样返回 404
+func taskErrorStatus(err error) int {
+	if errors.Is(err, models.ErrTaskNotFound) || errors.Is(err, models.ErrDependencyNotFound) ||
+		errors.Is(err, models.ErrChecklistItemNotFound) || errors.Is(err, models.ErrCommentNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrDependencyCycle) || errors.Is(err, services.ErrTaskBlocked) ||
+		errors.Is(err, services.ErrInvalidTransition) || errors.Is(err, services.ErrUndoConflict) ||
+		errors.Is(err, services.ErrBulkRolledBack) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, models.ErrVersionConflict) {
+		return http.StatusPreconditionFailed
+	}
+	if errors.Is(err, services.ErrOccurrenceNotFound) || errors.Is(err, services.ErrNothingToUndo) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrInvalidTaskQuery) || errors.Is(err, services.ErrInvalidTask) ||
+		errors.Is(err, services.ErrNotRecurring) || errors.Is(err, services.ErrInvalidProject) ||
+		errors.Is(err, services.ErrInvalidTag) || errors.Is(err, services.ErrInvalidComment) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// setTaskETag 以任务版本号作为 ETag 返回
+func setTaskETag(c *gin.Context, task *models.Task) {
+	c.Header("ETag", fmt.Sprintf("\"%d\"", task.Version))
+}
+
+// parseIfMatch 解析 If-Match 请求头中的版本号，未提供或为 * 时返回 0
+func parseIfMatch(c *gin.Context) (uint, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), "\"")
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("无效的If-Match")
+	}
+	return uint(version), nil
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTemplates 获取当前用户的任务模板
+func GetTemplates(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, err := services.ListTemplates(uid)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+// GetTemplate 获取单个任务模板
+func GetTemplate(c *gin.Context) {
+	uid, id, ok := parseTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	template, err := services.GetTemplate(uid, id)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": template})
+}
+
+// CreateTemplate 创建任务模板
+func CreateTemplate(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := services.CreateTemplate(uid, req)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": template})
+}
+
+// UpdateTemplate 修改任务模板
+func UpdateTemplate(c *gin.Context) {
+	uid, id, ok := parseTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := services.UpdateTemplate(uid, id, req)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": template})
+}
+
+// DeleteTemplate 删除任务模板
+func DeleteTemplate(c *gin.Context) {
+	uid, id, ok := parseTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteTemplate(uid, id); err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// InstantiateTemplate 以 anchor 为基准实例化模板，创建模板中的全部任务
+func InstantiateTemplate(c *gin.Context) {
+	uid, id, ok := parseTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	anchor, err := services.ParseTemplateAnchor(c.Query("anchor"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := services.InstantiateTemplate(uid, id, anchor, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": tasks})
+}
+
+func parseTemplateParams(c *gin.Context) (uint, uint, bool) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的模板ID"})
+		return 0, 0, false
+	}
+	return uid, uint(id), true
+}
+
+// templateErrorStatus 将模板服务的错误转换为 HTTP 状态码，实例化时创建任务的错误按任务错误处理
+func templateErrorStatus(err error) int {
+	if errors.Is(err, models.ErrTemplateNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, services.ErrTemplateExists) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, services.ErrInvalidTemplate) {
+		return http.StatusBadRequest
+	}
+	return taskErrorStatus(err)
+}
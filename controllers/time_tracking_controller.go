@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartTimer 为任务开始计时，请求体可省略
+func StartTimer(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TimerStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := services.StartTimer(uid, id, req)
+	if err != nil {
+		c.JSON(timeEntryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
+
+// StopTimer 停止当前用户运行中的计时器
+func StopTimer(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := services.StopTimer(uid)
+	if err != nil {
+		c.JSON(timeEntryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entry})
+}
+
+// GetRunningTimer 获取当前用户运行中的计时器，没有时 data 为 null
+func GetRunningTimer(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := services.GetRunningTimer(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entry})
+}
+
+// GetTimeEntries 获取任务的计时记录
+func GetTimeEntries(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	entries, err := services.ListTimeEntries(uid, id)
+	if err != nil {
+		c.JSON(timeEntryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entries})
+}
+
+// AddTimeEntry 为任务手动补录计时记录
+func AddTimeEntry(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := services.AddTimeEntry(uid, id, req)
+	if err != nil {
+		c.JSON(timeEntryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": entry})
+}
+
+// DeleteTimeEntry 删除任务的计时记录
+func DeleteTimeEntry(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+	entryID, err := strconv.ParseUint(c.Param("entry_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的计时记录ID"})
+		return
+	}
+
+	if err := services.DeleteTimeEntry(uid, id, uint(entryID)); err != nil {
+		c.JSON(timeEntryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// timeEntryErrorStatus 将计时服务的错误转换为 HTTP 状态码
+func timeEntryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrTimeEntryNotFound), errors.Is(err, services.ErrNoRunningTimer):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrTimerRunning):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidTimeEntry):
+		return http.StatusBadRequest
+	}
+	return taskErrorStatus(err)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"AITodo/models"
+	"AITodo/services"
+	"AITodo/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash 获取回收站中的任务
+func GetTrash(c *gin.Context) {
+	uid, err := util.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := services.ListTrash(uid)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tasks})
+}
+
+// RestoreTask 将任务移出回收站
+func RestoreTask(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	task, err := services.RestoreTask(uid, id, services.WriteOptions{Actor: models.UserActor(uid)})
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, gin.H{"data": task})
+}
+
+// PurgeTask 彻底删除回收站中的任务，无法恢复
+func PurgeTask(c *gin.Context) {
+	uid, id, ok := parseTaskParams(c)
+	if !ok {
+		return
+	}
+
+	if err := services.PurgeTask(uid, id); err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
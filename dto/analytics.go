@@ -32,17 +32,27 @@ type CategoryData struct {
 	Value int    `json:"value"` // 任务数量
 	Color string `json:"color"` // 颜色代码
 }
-type CategoryDistribution struct {
-	Work    int
-	Study   int
-	Life    int
-	Fitness int
-	Other   int
+
+// DefaultCategoryColor 未设置颜色或已删除的类别在图表中使用的颜色
+const DefaultCategoryColor = "#9E9E9E"
+
+// 标签分布
+type TagDistributionResponse struct {
+	Tags []TagStat `json:"tags"`
+}
+type TagStat struct {
+	Tag       string `json:"tag"`
+	Total     int    `json:"total"`
+	Pending   int    `json:"pending"`
+	Progress  int    `json:"in_progress"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
 }
 
 // 时段分析 热力图
 type HeatmapResponse struct {
-	TimeSlots []TimeSlot `json:"time_slots"`
+	TimeSlots  []TimeSlot  `json:"time_slots"`
+	FocusSlots []FocusSlot `json:"focus_slots"` // 番茄钟专注时长按小时分布
 }
 type TimeSlot struct {
 	Time  string `json:"time"`  // 时间段 (格式: HH:mm)
@@ -55,3 +65,43 @@ type CombinedResponse struct {
 	CategoryDistribution CategoryDistributionResponse `json:"category_distribution"`
 	Heatmap              HeatmapResponse              `json:"heatmap"`
 }
+
+// 预计与实际用时对比
+type TimeTrackingResponse struct {
+	TimeRange       string             `json:"time_range"` // day/week/month/year
+	Categories      []CategoryTimeStat `json:"categories"`
+	Periods         []PeriodTimeStat   `json:"periods"`
+	EstimateMinutes int                `json:"estimate_minutes"`
+	TrackedMinutes  int                `json:"tracked_minutes"`
+}
+type CategoryTimeStat struct {
+	Name            string `json:"name"`
+	Color           string `json:"color"`
+	EstimateMinutes int    `json:"estimate_minutes"` // 截止时间在统计范围内的任务的预计用时之和
+	TrackedMinutes  int    `json:"tracked_minutes"`  // 开始时间在统计范围内的计时记录的时长之和
+}
+type PeriodTimeStat struct {
+	Label           string `json:"label"`
+	EstimateMinutes int    `json:"estimate_minutes"`
+	TrackedMinutes  int    `json:"tracked_minutes"`
+}
+
+// 专注时长统计
+type FocusResponse struct {
+	TimeRange     string            `json:"time_range"` // day/week/month/year
+	Periods       []PeriodFocusStat `json:"periods"`
+	FocusSlots    []FocusSlot       `json:"focus_slots"`
+	FocusMinutes  int               `json:"focus_minutes"`
+	Pomodoros     int               `json:"pomodoros"`
+	Interruptions int               `json:"interruptions"`
+}
+type PeriodFocusStat struct {
+	Label         string `json:"label"`
+	FocusMinutes  int    `json:"focus_minutes"`
+	Pomodoros     int    `json:"pomodoros"`     // 完成的番茄钟数量
+	Interruptions int    `json:"interruptions"` // 按会话开始时间计入
+}
+type FocusSlot struct {
+	Time    string `json:"time"`    // 时间段 (格式: HH:00)
+	Minutes int    `json:"minutes"` // 该小时内的专注分钟数
+}
@@ -0,0 +1,8 @@
+package dto
+
+// AttachmentUsage 用户的附件用量，单位为字节
+type AttachmentUsage struct {
+	Used        int64 `json:"used"`
+	Quota       int64 `json:"quota"`
+	MaxFileSize int64 `json:"max_file_size"` // 单个附件的大小上限
+}
@@ -0,0 +1,42 @@
+package dto
+
+import "time"
+
+// 日历视图
+const (
+	CalendarViewDay   = "day"
+	CalendarViewWeek  = "week"
+	CalendarViewMonth = "month"
+)
+
+// CalendarResponse 按天排布的日历数据，窗口为 [Start, End)
+type CalendarResponse struct {
+	View       string        `json:"view"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Days       []CalendarDay `json:"days"`
+	AllDayRows int           `json:"all_day_rows"` // 全天区域需要的行数
+}
+
+// CalendarDay 日历中的一天
+// 全天任务和跨天任务放在 AllDay 中，当天内开始并结束的任务按时间放在 Timed 中
+type CalendarDay struct {
+	Date   string          `json:"date"` // 格式: 2006-01-02
+	AllDay []CalendarEntry `json:"all_day"`
+	Timed  []CalendarEntry `json:"timed"`
+}
+
+// CalendarEntry 任务的一次发生在某一天中的部分
+type CalendarEntry struct {
+	TaskOccurrence
+	AllDay          bool      `json:"all_day"`          // 是否为全天任务（00:00 开始、23:59 结束）
+	SegmentStart    time.Time `json:"segment_start"`    // 在当天内的开始时间
+	SegmentEnd      time.Time `json:"segment_end"`      // 在当天内的结束时间
+	StartMinute     int       `json:"start_minute"`     // 当天内的开始分钟数（0~1440）
+	EndMinute       int       `json:"end_minute"`       // 当天内的结束分钟数（0~1440）
+	ContinuesBefore bool      `json:"continues_before"` // 是否从前一天延续而来
+	ContinuesAfter  bool      `json:"continues_after"`  // 是否延续到后一天
+	Row             int       `json:"row"`              // 全天区域中的行号，同一次发生在各天保持一致
+	Lane            int       `json:"lane"`             // 定时区域中并列显示时所在的列
+	Lanes           int       `json:"lanes"`            // 与之时间重叠的一组任务共占的列数
+}
@@ -0,0 +1,13 @@
+package dto
+
+// CreateCategoryRequest 创建类别请求
+type CreateCategoryRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+// CategoryPatch 类别的部分更新请求，nil 字段表示不修改
+type CategoryPatch struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"AITodo/models"
+	"time"
+)
+
+// CommentRequest 创建或修改评论请求，body 为 Markdown 文本
+type CommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// 任务动态的类型
+const (
+	ActivityComment      = "comment"       // 评论
+	ActivityStatusChange = "status_change" // 状态变化，from_status 为空表示创建任务
+)
+
+// TaskActivity 任务动态，按 Type 填写 Comment 或 StatusChange 之一
+type TaskActivity struct {
+	Type         string                    `json:"type"`
+	Time         time.Time                 `json:"time"`
+	Comment      *models.TaskComment       `json:"comment,omitempty"`
+	StatusChange *models.TaskStatusHistory `json:"status_change,omitempty"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// FocusStartRequest 开始番茄钟会话请求，各项为 0 时使用默认值
+type FocusStartRequest struct {
+	WorkMinutes      int `json:"work_minutes"`       // 每个番茄钟的专注时长，默认25分钟
+	BreakMinutes     int `json:"break_minutes"`      // 短休息时长，默认5分钟
+	LongBreakMinutes int `json:"long_break_minutes"` // 长休息时长，默认15分钟
+	LongBreakEvery   int `json:"long_break_every"`   // 每完成几个番茄钟进行一次长休息，默认4
+}
@@ -0,0 +1,21 @@
+package dto
+
+// CreateProjectRequest 创建项目请求
+type CreateProjectRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+}
+
+// ProjectPatch 项目的部分更新请求，nil 字段表示不修改
+type ProjectPatch struct {
+	Name     *string `json:"name"`
+	Color    *string `json:"color"`
+	Icon     *string `json:"icon"`
+	Archived *bool   `json:"archived"`
+}
+
+// ReorderProjectsRequest 调整项目顺序请求，ids 为用户全部项目按新顺序排列的ID
+type ReorderProjectsRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
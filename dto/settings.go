@@ -0,0 +1,6 @@
+package dto
+
+// OverduePolicyRequest 修改逾期处理策略的请求
+type OverduePolicyRequest struct {
+	Policy string `json:"policy" binding:"required"` // fail / rollover / leave
+}
@@ -0,0 +1,11 @@
+package dto
+
+// TagRequest 创建或重命名标签请求
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergeTagRequest 合并标签请求，当前标签合并到 into_id 后被删除
+type MergeTagRequest struct {
+	IntoID uint `json:"into_id" binding:"required"`
+}
@@ -0,0 +1,225 @@
+package dto
+
+import (
+	"AITodo/models"
+	"encoding/json"
+	"time"
+)
+
+// TaskQuery 任务列表查询参数
+type TaskQuery struct {
+	Cursor    string `form:"cursor"`     // 上一页返回的 next_cursor
+	Limit     int    `form:"limit"`      // 每页数量，默认20，最大100
+	Status    string `form:"status"`     // 状态，多个用逗号分隔
+	Priority  string `form:"priority"`   // 优先级，多个用逗号分隔
+	Category  string `form:"category"`   // 类别，多个用逗号分隔
+	Project   string `form:"project"`    // 项目ID，多个用逗号分隔，none 表示不属于任何项目
+	Location  string `form:"location"`   // 地点，模糊匹配
+	StartFrom string `form:"start_from"` // 开始时间下限
+	StartTo   string `form:"start_to"`   // 开始时间上限
+	DueFrom   string `form:"due_from"`   // 截止时间下限
+	DueTo     string `form:"due_to"`     // 截止时间上限
+	Overdue   bool   `form:"overdue"`    // 仅返回已逾期未完成的任务
+	Q         string `form:"q"`          // 在标题和描述中搜索
+	Tags      string `form:"tags"`       // 标签布尔查询，例如 tag:urgent AND NOT tag:waiting
+	Sort      string `form:"sort"`       // 排序字段，逗号分隔，前缀 - 表示降序，例如 due_date,-created_at
+}
+
+// TaskListResponse 任务列表分页响应
+type TaskListResponse struct {
+	Data       []models.Task `json:"data"`
+	NextCursor string        `json:"next_cursor"` // 为空表示没有下一页
+	Total      int64         `json:"total"`       // 满足筛选条件的任务总数
+}
+
+// TaskPatch 任务的部分更新请求，nil 字段表示不修改，值为 null 的字段同样视为不修改
+// 例外：important、urgent 为 null 时清除用户的标记，恢复为按优先级和截止时间推断
+type TaskPatch struct {
+	Title           *string      `json:"title"`
+	Category        *string      `json:"category"`
+	Location        *string      `json:"location"`
+	Description     *string      `json:"description"`
+	Status          *string      `json:"status"`
+	Priority        *string      `json:"priority"`
+	Important       NullableBool `json:"important"`
+	Urgent          NullableBool `json:"urgent"`
+	StartDate       *time.Time   `json:"start_date"`
+	DueDate         *time.Time   `json:"due_date"`
+	Recurrence      *string      `json:"recurrence"`       // 空字符串表示取消重复
+	ProjectID       *uint        `json:"project_id"`       // 0 表示移出项目
+	EstimateMinutes *int         `json:"estimate_minutes"` // 预计用时（分钟），0 表示清除估计
+	Tags            *[]string    `json:"tags"`             // 替换为给出的标签名称，空数组表示清空标签
+	Rank            *string      `json:"-"`                // 看板排序值，只能通过看板移动接口修改
+}
+
+// NullableBool 区分未提供、显式为 null 和具体取值的布尔字段
+// Set 为 true 表示请求中包含该字段，此时 Value 为 nil 表示 null
+type NullableBool struct {
+	Set   bool
+	Value *bool
+}
+
+// UnmarshalJSON 只有字段出现在请求中时才会被调用，因此可以据此设置 Set
+func (n *NullableBool) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	n.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+	var value bool
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
+// MarshalJSON 与 *bool 的序列化结果一致
+func (n NullableBool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Value)
+}
+
+// TaskOccurrence 时间窗口内的一次任务发生
+// 重复任务展开出的发生沿用重复任务的ID，StartDate/DueDate 为该次发生的时间
+type TaskOccurrence struct {
+	models.Task
+	OccurrenceAt time.Time `json:"occurrence_at"` // 原始发生时间，编辑、完成或删除单次发生时使用
+	IsRecurring  bool      `json:"is_recurring"`  // 是否属于重复任务（包括例外实例）
+}
+
+// TaskNode 任务树节点
+type TaskNode struct {
+	models.Task
+	Progress TaskProgress `json:"progress"`
+	Children []*TaskNode  `json:"children"`
+}
+
+// TaskProgress 子任务完成进度，按叶子任务统计，叶子任务统计自身
+type TaskProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// MoveTaskRequest 移动子树请求，parent_id 为 null 表示移动到顶层
+type MoveTaskRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+// AddDependencyRequest 添加前置任务请求
+type AddDependencyRequest struct {
+	BlockedByID uint `json:"blocked_by_id" binding:"required"`
+}
+
+// TaskDependencies 任务的前置任务和后续任务
+type TaskDependencies struct {
+	BlockedBy []models.Task `json:"blocked_by"` // 阻塞该任务的前置任务
+	Blocking  []models.Task `json:"blocking"`   // 被该任务阻塞的后续任务
+}
+
+// ScheduleItem 关键路径计算中单个任务的最早开始/完成时间
+type ScheduleItem struct {
+	TaskID         uint      `json:"task_id"`
+	Title          string    `json:"title"`
+	EarliestStart  time.Time `json:"earliest_start"`
+	EarliestFinish time.Time `json:"earliest_finish"`
+	Late           bool      `json:"late"` // 最早完成时间晚于截止时间
+}
+
+// CriticalPathResponse 关键路径计算结果
+type CriticalPathResponse struct {
+	Path     []ScheduleItem `json:"path"`     // 关键路径，按执行顺序排列
+	Finish   time.Time      `json:"finish"`   // 关键路径的最早完成时间
+	Schedule []ScheduleItem `json:"schedule"` // 参与计算的全部任务
+}
+
+// TrashedTask 回收站中的任务
+type TrashedTask struct {
+	models.Task
+	PurgeAt time.Time `json:"purge_at"` // 到期后自动彻底删除
+}
+
+// BulkTaskRequest 批量操作请求
+type BulkTaskRequest struct {
+	Mode       string          `json:"mode"` // atomic（默认）：任一失败全部回滚；best_effort：跳过失败的操作
+	Operations []BulkOperation `json:"operations" binding:"required,min=1,dive"`
+}
+
+// BulkOperation 批量操作中的单个操作
+type BulkOperation struct {
+	Op          string       `json:"op" binding:"required"` // create / update / delete / status
+	ID          uint         `json:"id"`                    // update、delete、status 操作的任务ID
+	Version     uint         `json:"version"`               // 期望的版本号，等价于 If-Match，可选
+	Force       bool         `json:"force"`                 // 允许完成仍有未完成前置任务的任务
+	Task        *models.Task `json:"task"`                  // create 操作的任务
+	ProjectName string       `json:"project_name"`          // create 操作按名称指定项目，不存在时自动创建，可选
+	Patch       *TaskPatch   `json:"patch"`                 // update 操作修改的字段
+	Status      string       `json:"status"`                // status 操作的目标状态
+	Children    string       `json:"children"`              // delete 操作的子任务处理方式
+}
+
+// BulkResult 单个操作的执行结果
+type BulkResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	ID     uint         `json:"id,omitempty"`
+	Status int          `json:"status"` // 与单独调用对应接口时相同的 HTTP 状态码
+	Task   *models.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// BulkTaskResponse 批量操作的响应
+type BulkTaskResponse struct {
+	Mode      string       `json:"mode"`
+	Committed bool         `json:"committed"` // 是否有修改被提交
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+// EisenhowerMatrix 按重要和紧急程度划分的四象限，只包含未完成的任务
+type EisenhowerMatrix struct {
+	DoFirst   []models.Task `json:"do_first"`  // 重要且紧急：立即处理
+	Schedule  []models.Task `json:"schedule"`  // 重要不紧急：安排时间处理
+	Delegate  []models.Task `json:"delegate"`  // 紧急不重要：委托或尽快处理
+	Eliminate []models.Task `json:"eliminate"` // 不重要不紧急：考虑删除
+}
+
+// TaskChecklist 任务的检查项及完成进度
+type TaskChecklist struct {
+	Items    []models.ChecklistItem   `json:"items"`
+	Progress models.ChecklistProgress `json:"progress"`
+	Version  uint                     `json:"version"` // 任务当前的版本号，与任务的 ETag 一致
+}
+
+// ChecklistItemRequest 添加检查项请求
+type ChecklistItemRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ChecklistItemPatch 修改检查项请求，nil 字段表示不修改
+type ChecklistItemPatch struct {
+	Content *string `json:"content"`
+	Done    *bool   `json:"done"`
+}
+
+// ReorderChecklistRequest 调整检查项顺序请求，ids 为任务全部检查项按新顺序排列的ID
+type ReorderChecklistRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// BoardColumn 看板中的一列，Total 为该列满足筛选条件的任务总数
+type BoardColumn struct {
+	Status string        `json:"status"`
+	Tasks  []models.Task `json:"tasks"`
+	Total  int64         `json:"total"`
+}
+
+// BoardMoveRequest 看板移动请求，after_id 为移动后紧挨在上方的任务，before_id 为紧挨在下方的任务，
+// 两者都不提供时移动到列尾
+type BoardMoveRequest struct {
+	Status   string `json:"status"` // 目标列的状态，为空表示在当前列内移动
+	AfterID  *uint  `json:"after_id"`
+	BeforeID *uint  `json:"before_id"`
+	Version  uint   `json:"version"` // 期望的版本号，等价于 If-Match，可选
+	Force    bool   `json:"force"`   // 允许完成仍有未完成前置任务的任务
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "AITodo/models"
+
+// TemplateRequest 创建或修改模板请求，修改时 items 整体替换模板中原有的任务
+type TemplateRequest struct {
+	Name        string                    `json:"name" binding:"required"`
+	Description string                    `json:"description"`
+	ProjectName string                    `json:"project_name"`
+	Items       []models.TaskTemplateItem `json:"items" binding:"required"`
+}
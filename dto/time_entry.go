@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"AITodo/models"
+	"time"
+)
+
+// TimerStartRequest 开始计时请求
+// 已有运行中的计时器时，switch 为 true 则先停止原计时器，否则拒绝开始
+type TimerStartRequest struct {
+	Note   string `json:"note"`
+	Switch bool   `json:"switch"`
+}
+
+// TimerStartResult 开始计时的结果，Stopped 为因切换而停止的计时器
+type TimerStartResult struct {
+	Entry   *models.TimeEntry `json:"entry"`
+	Stopped *models.TimeEntry `json:"stopped,omitempty"`
+}
+
+// TimeEntryRequest 手动补录计时记录请求
+type TimeEntryRequest struct {
+	StartedAt time.Time `json:"started_at" binding:"required"`
+	EndedAt   time.Time `json:"ended_at" binding:"required"`
+	Note      string    `json:"note"`
+}
+
+// TaskTimeEntries 任务的计时记录及汇总
+type TaskTimeEntries struct {
+	Entries         []models.TimeEntry `json:"entries"`
+	TrackedSeconds  int64              `json:"tracked_seconds"`  // 全部记录的总时长，包括运行中的计时器
+	EstimateMinutes int                `json:"estimate_minutes"` // 任务的预计用时，0 表示未估计
+}
@@ -6,6 +6,7 @@ import (
 	"AITodo/globals"
 	"AITodo/models"
 	"AITodo/routes"
+	"AITodo/services"
 	"AITodo/util"
 	"github.com/gin-gonic/gin"
 	"github.com/panjf2000/ants/v2"
@@ -33,16 +34,38 @@ func main() {
 		logrus.Fatal(err)
 	}
 
-	err = db.DB.AutoMigrate(&models.Task{}, &models.User{})
+	// 任务与标签的关联表使用 TaskTag 模型
+	if err = db.DB.SetupJoinTable(&models.Task{}, "Tags", &models.TaskTag{}); err != nil {
+		logrus.Fatalf("数据库迁移失败: %v", err)
+	}
+	err = db.DB.AutoMigrate(&models.Task{}, &models.User{}, &models.TaskDependency{}, &models.TaskStatusHistory{},
+		&models.TaskOverdueRecord{}, &models.TaskAudit{}, &models.Project{}, &models.Category{},
+		&models.Tag{}, &models.TaskTag{}, &models.ChecklistItem{}, &models.TaskComment{}, &models.Attachment{},
+		&models.TaskTemplate{}, &models.TaskTemplateItem{}, &models.TimeEntry{},
+		&models.FocusSession{}, &models.FocusSegment{})
 	if err != nil {
 		logrus.Fatalf("数据库迁移失败: %v", err) // 终止程序
 	}
+	// 为升级前创建的任务分配看板排序值
+	if err = models.BackfillTaskRanks(); err != nil {
+		logrus.Fatalf("数据库迁移失败: %v", err)
+	}
 
 	util.JwtSecret, util.PublicKey, err = util.ReadPEM("private_key.pem")
 	if err != nil {
 		logrus.Fatalf("私钥读取失败:%v", err)
 	}
 
+	// 初始化附件存储
+	if err = util.InitStorage(config.Cfg.Storage); err != nil {
+		logrus.Fatalf("附件存储初始化失败: %v", err)
+	}
+
+	// 启动逾期任务后台扫描
+	services.StartOverdueSweeper(config.Cfg.Job.OverdueSweepInterval)
+	// 启动回收站自动清理
+	services.StartTrashPurger(config.Cfg.Job.TrashPurgeInterval, config.Cfg.Job.TrashRetention)
+
 	//初始化gin
 	router := gin.Default()
 
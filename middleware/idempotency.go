@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"AITodo/util"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// bodyRecorder 在写出响应的同时记录响应内容，流式响应也会完整记录
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 根据 Idempotency-Key 请求头对请求去重，需在 JWTAuth 之后使用
+// 同一用户在 ttl 内以相同请求体重试时直接重放首次的响应；以不同请求体复用幂等键返回 422，原请求仍在处理中返回 409
+// 未携带该请求头的请求不受影响
+func Idempotency(ttl, pendingTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key过长"})
+			return
+		}
+
+		uid, err := util.GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// 幂等键按用户和接口隔离，请求指纹包含方法、实际路径、查询参数和请求体
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.RequestURI()+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+		redisKey := strconv.FormatUint(uint64(uid), 10) + ":" + c.FullPath() + ":" + key
+
+		existing, ok, err := util.ReserveIdempotencyKey(redisKey, hash, pendingTTL)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "幂等键校验失败:" + err.Error()})
+			return
+		}
+		if !ok {
+			switch {
+			case existing.Hash != hash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key已被用于不同的请求"})
+			case !existing.Done:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "相同Idempotency-Key的请求正在处理中"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.Status, existing.ContentType, existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 处理函数上报了修改未提交，或未上报时出现服务端错误、响应不完整，不保存结果，释放幂等键以便客户端重试
+		status := recorder.Status()
+		commit, reported := util.GetIdempotencyCommit(c)
+		incomplete := util.IsResponseIncomplete(c)
+		if (reported && !commit.Committed) || (!reported && (incomplete || status >= http.StatusInternalServerError)) {
+			if err := util.ReleaseIdempotencyKey(redisKey); err != nil {
+				log.Printf("释放幂等键 %s 失败: %v", redisKey, err)
+			}
+			return
+		}
+		resp := util.IdempotentResponse{
+			Hash:        hash,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		// 修改已提交但响应中途出错时不保存截断的响应，重试时返回已提交的批次，不会再次执行
+		if incomplete {
+			body, err := json.Marshal(gin.H{"data": gin.H{"batch_id": commit.BatchID, "committed": true}})
+			if err != nil {
+				log.Printf("序列化幂等响应 %s 失败: %v", redisKey, err)
+				return
+			}
+			resp.Status, resp.ContentType, resp.Body = http.StatusOK, "application/json; charset=utf-8", body
+		}
+		if err := util.SaveIdempotentResponse(redisKey, resp, ttl); err != nil {
+			log.Printf("保存幂等响应 %s 失败: %v", redisKey, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAttachmentNotFound 附件不存在或不属于当前用户
+var ErrAttachmentNotFound = errors.New("附件不存在")
+
+// Attachment 任务的附件，文件内容保存在存储后端的 StorageKey 处
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      uint      `gorm:"index" json:"task_id"`
+	UserID      uint      `gorm:"index" json:"-"`
+	FileName    string    `gorm:"size:255;not null" json:"file_name"`
+	ContentType string    `gorm:"size:100;not null" json:"content_type"` // 根据文件内容识别的类型
+	Size        int64     `gorm:"not null" json:"size"`
+	StorageKey  string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	DownloadURL string    `gorm:"-" json:"download_url"` // 带签名的临时下载地址
+}
+
+// ListAttachments 获取任务的附件，按上传时间排列
+func ListAttachments(userID, taskID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).Order("id").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetAttachment 获取附件
+func GetAttachment(id uint) (*Attachment, error) {
+	var attachment Attachment
+	err := db.DB.First(&attachment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttachmentNotFound
+	}
+	return &attachment, err
+}
+
+// GetAttachmentTx 在指定事务中获取任务的附件
+func GetAttachmentTx(tx *gorm.DB, userID, taskID, id uint) (*Attachment, error) {
+	var attachment Attachment
+	err := tx.Where("user_id = ? AND task_id = ?", userID, taskID).First(&attachment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttachmentNotFound
+	}
+	return &attachment, err
+}
+
+// AttachmentUsageTx 在指定事务中统计用户附件占用的总字节数，lock 为 true 时锁定用户记录，
+// 使同一用户的并发上传依次进行配额检查
+func AttachmentUsageTx(tx *gorm.DB, userID uint, lock bool) (int64, error) {
+	if lock {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return 0, err
+		}
+	}
+	var used int64
+	err := tx.Model(&Attachment{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// CreateAttachmentTx 在指定事务中创建附件记录
+func CreateAttachmentTx(tx *gorm.DB, attachment *Attachment) error {
+	return tx.Create(attachment).Error
+}
+
+// DeleteAttachmentTx 在指定事务中删除附件记录
+func DeleteAttachmentTx(tx *gorm.DB, attachment *Attachment) error {
+	result := tx.Where("user_id = ?", attachment.UserID).Delete(&Attachment{}, attachment.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// attachmentKeysTx 获取任务附件在存储后端中的位置
+func attachmentKeysTx(tx *gorm.DB, userID uint, taskIDs []uint) ([]string, error) {
+	var keys []string
+	err := tx.Model(&Attachment{}).Where("user_id = ? AND task_id IN ?", userID, taskIDs).
+		Pluck("storage_key", &keys).Error
+	return keys, err
+}
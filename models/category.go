@@ -0,0 +1,114 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultCategoryName 默认类别，不能删除或重命名，删除其他类别时其中的任务归入该类别
+const DefaultCategoryName = "其他"
+
+// ErrCategoryNotFound 类别不存在或不属于当前用户
+var ErrCategoryNotFound = errors.New("类别不存在")
+
+// defaultCategories 用户第一次使用类别时创建的初始类别
+var defaultCategories = []Category{
+	{Name: "工作", Color: "#4CAF50"},
+	{Name: "学习", Color: "#2196F3"},
+	{Name: "生活", Color: "#FFC107"},
+	{Name: "健身", Color: "#F44336"},
+	{Name: DefaultCategoryName, Color: "#9E9E9E"},
+}
+
+// Category 用户自定义的任务类别，任务通过名称引用类别
+type Category struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_category_user_name" json:"user_id"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex:idx_category_user_name" json:"name"`
+	Color     string    `gorm:"size:20" json:"color"` // 颜色代码，例如 #4CAF50
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ListCategories 获取用户的全部类别，用户还没有类别时先创建初始类别
+func ListCategories(userID uint) ([]Category, error) {
+	return ListCategoriesTx(db.DB, userID)
+}
+
+// ListCategoriesTx 在指定事务中获取用户的全部类别
+func ListCategoriesTx(tx *gorm.DB, userID uint) ([]Category, error) {
+	var categories []Category
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	if len(categories) > 0 {
+		return categories, nil
+	}
+
+	categories = make([]Category, len(defaultCategories))
+	for i, c := range defaultCategories {
+		categories[i] = Category{UserID: userID, Name: c.Name, Color: c.Color}
+	}
+	// 并发请求可能同时创建初始类别，依靠唯一索引忽略重复的插入
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&categories).Error; err != nil {
+		return nil, err
+	}
+	categories = nil
+	err := tx.Where("user_id = ?", userID).Order("id").Find(&categories).Error
+	return categories, err
+}
+
+// GetCategoryById 获取用户的类别
+func GetCategoryById(userID, id uint) (*Category, error) {
+	var category Category
+	err := db.DB.Where("user_id = ?", userID).First(&category, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	return &category, err
+}
+
+// CreateCategory 创建类别
+func CreateCategory(category *Category) error {
+	return db.DB.Create(category).Error
+}
+
+// UpdateCategory 保存类别的名称和颜色，名称变化时同步修改引用该类别的任务（包括回收站中的任务）
+func UpdateCategory(category *Category, oldName string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(category).Where("user_id = ?", category.UserID).
+			Select("name", "color").Updates(category).Error; err != nil {
+			return err
+		}
+		if category.Name == oldName {
+			return nil
+		}
+		return reassignCategoryTx(tx, category.UserID, oldName, category.Name)
+	})
+}
+
+// DeleteCategory 删除类别，其中的任务归入默认类别
+func DeleteCategory(category *Category) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", category.UserID).Delete(&Category{}, category.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+		return reassignCategoryTx(tx, category.UserID, category.Name, DefaultCategoryName)
+	})
+}
+
+func reassignCategoryTx(tx *gorm.DB, userID uint, from, to string) error {
+	return tx.Unscoped().Model(&Task{}).Scopes(OwnedBy(userID)).Where("tasks.category = ?", from).
+		Updates(map[string]interface{}{
+			"category": to,
+			"version":  gorm.Expr("version + 1"),
+		}).Error
+}
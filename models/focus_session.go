@@ -0,0 +1,120 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 专注会话的阶段
+const (
+	FocusPhaseWork       = "work"        // 专注中
+	FocusPhaseShortBreak = "short_break" // 短休息
+	FocusPhaseLongBreak  = "long_break"  // 长休息
+	FocusPhaseIdle       = "idle"        // 休息结束，等待开始下一个番茄钟
+	FocusPhaseFinished   = "finished"    // 会话已结束
+)
+
+// FocusSession 与任务关联的番茄钟会话，计时状态保存在服务端，换设备后可以继续
+// 当前阶段从 PhaseStartedAt 开始运行，PhaseElapsed 为暂停前已经过的秒数，PausedAt 不为空表示已暂停
+// Active 在会话进行中时为 true、结束后为 NULL，配合 (user_id, active) 唯一索引保证每个用户最多一个进行中的会话
+type FocusSession struct {
+	ID                 uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID             uint       `gorm:"index" json:"task_id"`
+	UserID             uint       `gorm:"index;uniqueIndex:idx_focus_session_active,priority:1" json:"-"`
+	WorkMinutes        int        `gorm:"not null" json:"work_minutes"`
+	BreakMinutes       int        `gorm:"not null" json:"break_minutes"`
+	LongBreakMinutes   int        `gorm:"not null" json:"long_break_minutes"`
+	LongBreakEvery     int        `gorm:"not null" json:"long_break_every"` // 每完成几个番茄钟进行一次长休息
+	Phase              string     `gorm:"size:20;not null" json:"phase"`
+	PhaseStartedAt     time.Time  `gorm:"not null" json:"phase_started_at"`
+	PhaseElapsed       int64      `gorm:"not null;default:0" json:"-"`
+	PausedAt           *time.Time `json:"paused_at"`
+	CompletedPomodoros int        `gorm:"not null;default:0" json:"completed_pomodoros"`
+	Interruptions      int        `gorm:"not null;default:0" json:"interruptions"`
+	FocusSeconds       int64      `gorm:"not null;default:0" json:"focus_seconds"` // 累计专注时长，不含休息
+	StartedAt          time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt            *time.Time `json:"ended_at"`
+	Active             *bool      `gorm:"uniqueIndex:idx_focus_session_active,priority:2" json:"-"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	RemainingSeconds   int64      `gorm:"-" json:"remaining_seconds"` // 当前阶段剩余的秒数，由服务层计算
+}
+
+// FocusSegment 一段连续的专注时间，暂停或结束会话时截断，用于按时间统计专注时长
+type FocusSegment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SessionID uint      `gorm:"index" json:"session_id"`
+	TaskID    uint      `gorm:"index" json:"task_id"`
+	UserID    uint      `gorm:"index" json:"-"`
+	StartedAt time.Time `gorm:"not null;index" json:"started_at"`
+	EndedAt   time.Time `gorm:"not null" json:"ended_at"`
+	Seconds   int64     `gorm:"not null" json:"seconds"`
+	Completed bool      `gorm:"not null;default:false" json:"completed"` // 是否以完成一个番茄钟结束
+}
+
+// PhaseLength 当前阶段的总时长
+func (s *FocusSession) PhaseLength() time.Duration {
+	switch s.Phase {
+	case FocusPhaseWork:
+		return time.Duration(s.WorkMinutes) * time.Minute
+	case FocusPhaseShortBreak:
+		return time.Duration(s.BreakMinutes) * time.Minute
+	case FocusPhaseLongBreak:
+		return time.Duration(s.LongBreakMinutes) * time.Minute
+	default:
+		return 0
+	}
+}
+
+// ListFocusSessions 获取任务的专注会话，按开始时间倒序排列
+func ListFocusSessions(userID, taskID uint) ([]FocusSession, error) {
+	var sessions []FocusSession
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).
+		Order("started_at DESC, id DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// GetActiveFocusSessionTx 在指定事务中获取用户进行中的专注会话，没有时返回 nil
+// lock 为 true 时锁定用户记录，使同一用户对会话的操作依次进行
+func GetActiveFocusSessionTx(tx *gorm.DB, userID uint, lock bool) (*FocusSession, error) {
+	if lock {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return nil, err
+		}
+	}
+	var session FocusSession
+	err := tx.Where("user_id = ? AND active = ?", userID, true).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CreateFocusSessionTx 在指定事务中创建进行中的专注会话
+func CreateFocusSessionTx(tx *gorm.DB, session *FocusSession) error {
+	active := true
+	session.Active = &active
+	return tx.Create(session).Error
+}
+
+// SaveTx 在指定事务中保存会话状态，会话结束时释放进行中标记
+func (s *FocusSession) SaveTx(tx *gorm.DB) error {
+	if s.Phase == FocusPhaseFinished {
+		s.Active = nil
+	}
+	return tx.Model(s).Select("phase", "phase_started_at", "phase_elapsed", "paused_at", "completed_pomodoros",
+		"interruptions", "focus_seconds", "ended_at", "active", "updated_at").Updates(s).Error
+}
+
+// CreateFocusSegmentTx 在指定事务中记录一段专注时间
+func CreateFocusSegmentTx(tx *gorm.DB, segment *FocusSegment) error {
+	return tx.Create(segment).Error
+}
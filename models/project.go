@@ -0,0 +1,144 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrProjectNotFound 项目不存在或不属于当前用户
+var ErrProjectNotFound = errors.New("项目不存在")
+
+// Project 用户自定义的项目（清单），用于对任务分组
+type Project struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"index;uniqueIndex:idx_project_user_name" json:"user_id"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex:idx_project_user_name" json:"name"`
+	Color     string    `gorm:"size:20" json:"color"` // 颜色代码，例如 #4CAF50
+	Icon      string    `gorm:"size:50" json:"icon"`
+	Archived  bool      `gorm:"not null;default:false" json:"archived"` // 归档的项目不在列表中显示，也不能再添加任务
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`   // 显示顺序，从小到大
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ListProjects 按显示顺序获取用户的项目，includeArchived 为 false 时不返回已归档的项目
+func ListProjects(userID uint, includeArchived bool) ([]Project, error) {
+	query := db.DB.Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("archived = ?", false)
+	}
+	var projects []Project
+	err := query.Order("sort_order").Order("id").Find(&projects).Error
+	return projects, err
+}
+
+// GetProjectByIdTx 在指定事务中获取用户的项目
+func GetProjectByIdTx(tx *gorm.DB, userID, id uint) (*Project, error) {
+	var project Project
+	err := tx.Where("user_id = ?", userID).First(&project, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	return &project, err
+}
+
+// GetProjectByNameTx 在指定事务中按名称获取用户的项目
+func GetProjectByNameTx(tx *gorm.DB, userID uint, name string) (*Project, error) {
+	var project Project
+	err := tx.Where("user_id = ? AND name = ?", userID, name).First(&project).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	return &project, err
+}
+
+// CreateProjectTx 在指定事务中创建项目，排在现有项目之后
+func CreateProjectTx(tx *gorm.DB, project *Project) error {
+	if err := setNextSortOrder(tx, project); err != nil {
+		return err
+	}
+	return tx.Create(project).Error
+}
+
+// FindOrCreateProjectTx 在指定事务中创建项目，同名项目已存在时返回已有的项目
+// 并发请求可能同时创建同名项目，依靠唯一索引忽略重复的插入
+func FindOrCreateProjectTx(tx *gorm.DB, project *Project) (*Project, error) {
+	if err := setNextSortOrder(tx, project); err != nil {
+		return nil, err
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(project)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return project, nil
+	}
+	// 加锁读取，以读到其他事务刚提交的项目
+	return GetProjectByNameTx(tx.Clauses(clause.Locking{Strength: "SHARE"}), project.UserID, project.Name)
+}
+
+// setNextSortOrder 将项目排在用户现有项目之后
+func setNextSortOrder(tx *gorm.DB, project *Project) error {
+	var maxOrder *int
+	if err := tx.Model(&Project{}).Where("user_id = ?", project.UserID).
+		Select("MAX(sort_order)").Scan(&maxOrder).Error; err != nil {
+		return err
+	}
+	project.SortOrder = 0
+	if maxOrder != nil {
+		project.SortOrder = *maxOrder + 1
+	}
+	return nil
+}
+
+// UpdateColumns 只更新项目的指定列
+func (p *Project) UpdateColumns(columns ...string) error {
+	return db.DB.Model(p).Where("user_id = ?", p.UserID).Select(columns).Updates(p).Error
+}
+
+// ReorderProjects 按 ids 的顺序重新设置项目的显示顺序，ids 必须包含用户的全部项目
+func ReorderProjects(userID uint, ids []uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Project{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return ErrProjectNotFound
+		}
+		for i, id := range ids {
+			result := tx.Model(&Project{}).Where("user_id = ? AND id = ?", userID, id).Update("sort_order", i)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrProjectNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteProjectTx 在指定事务中删除项目，不修改项目中的任务
+func DeleteProjectTx(tx *gorm.DB, userID, id uint) error {
+	result := tx.Where("user_id = ?", userID).Delete(&Project{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// ListProjectTasksTx 在指定事务中获取项目中的全部任务，包括回收站中的任务
+func ListProjectTasksTx(tx *gorm.DB, userID, id uint) ([]Task, error) {
+	var tasks []Task
+	err := tx.Unscoped().Scopes(OwnedBy(userID)).Where("tasks.project_id = ?", id).
+		Order("tasks.id").Find(&tasks).Error
+	return tasks, err
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTagNotFound 标签不存在或不属于当前用户
+var ErrTagNotFound = errors.New("标签不存在")
+
+// Tag 用户的任务标签，与任务为多对多关系
+type Tag struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_user_name" json:"-"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex:idx_tag_user_name" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
+}
+
+// TaskTag 任务与标签的关联
+type TaskTag struct {
+	TaskID uint `gorm:"primaryKey"`
+	TagID  uint `gorm:"primaryKey;index"`
+}
+
+// TagCount 标签及使用该标签的任务数量（不含回收站中的任务）
+type TagCount struct {
+	Tag
+	TaskCount int `json:"task_count"`
+}
+
+// TagExpr 标签布尔查询表达式
+// Op 为 tag 时匹配带有标签 Tag 的任务，为 and/or 时组合 Args，为 not 时对 Args[0] 取反
+type TagExpr struct {
+	Op   string
+	Tag  string
+	Args []TagExpr
+}
+
+// 标签表达式的运算符
+const (
+	TagExprTag = "tag"
+	TagExprAnd = "and"
+	TagExprOr  = "or"
+	TagExprNot = "not"
+)
+
+// SQL 将表达式转换为针对 tasks 表的 SQL 条件
+func (e TagExpr) SQL() (string, []interface{}) {
+	switch e.Op {
+	case TagExprTag:
+		return `EXISTS (SELECT 1 FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+			WHERE task_tags.task_id = tasks.id AND tags.name = ?)`, []interface{}{e.Tag}
+	case TagExprNot:
+		sql, args := e.Args[0].SQL()
+		return "NOT (" + sql + ")", args
+	default:
+		sep := " AND "
+		if e.Op == TagExprOr {
+			sep = " OR "
+		}
+		parts := make([]string, len(e.Args))
+		var args []interface{}
+		for i, arg := range e.Args {
+			sql, argArgs := arg.SQL()
+			parts[i] = "(" + sql + ")"
+			args = append(args, argArgs...)
+		}
+		return strings.Join(parts, sep), args
+	}
+}
+
+// ListTags 按名称获取用户的全部标签及其任务数量
+func ListTags(userID uint) ([]TagCount, error) {
+	var tags []TagCount
+	err := db.DB.Model(&Tag{}).
+		Select("tags.*, COUNT(tasks.id) AS task_count").
+		Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+		Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// GetTagById 获取用户的标签
+func GetTagById(userID, id uint) (*Tag, error) {
+	var tag Tag
+	err := db.DB.Where("user_id = ?", userID).First(&tag, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTagNotFound
+	}
+	return &tag, err
+}
+
+// GetTagByName 按名称获取用户的标签
+func GetTagByName(userID uint, name string) (*Tag, error) {
+	var tag Tag
+	err := db.DB.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTagNotFound
+	}
+	return &tag, err
+}
+
+// FindOrCreateTagsTx 在指定事务中按名称获取标签，不存在的标签自动创建，结果与 names 顺序一致
+func FindOrCreateTagsTx(tx *gorm.DB, userID uint, names []string) ([]Tag, error) {
+	if len(names) == 0 {
+		return []Tag{}, nil
+	}
+	tags := make([]Tag, len(names))
+	for i, name := range names {
+		tags[i] = Tag{UserID: userID, Name: name}
+	}
+	// 并发请求可能同时创建同名标签，依靠唯一索引忽略重复的插入
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var existing []Tag
+	if err := tx.Where("user_id = ? AND name IN ?", userID, names).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Tag, len(existing))
+	for _, tag := range existing {
+		byName[tag.Name] = tag
+	}
+	for i, name := range names {
+		tags[i] = byName[name]
+	}
+	return tags, nil
+}
+
+// SetTaskTagsTx 在指定事务中将任务的标签替换为 tags
+func SetTaskTagsTx(tx *gorm.DB, taskID uint, tags []Tag) error {
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	query := tx.Where("task_id = ?", taskID)
+	if len(ids) > 0 {
+		query = query.Where("tag_id NOT IN ?", ids)
+	}
+	if err := query.Delete(&TaskTag{}).Error; err != nil {
+		return err
+	}
+	return addTaskTagsTx(tx, taskID, ids)
+}
+
+func addTaskTagsTx(tx *gorm.DB, taskID uint, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	links := make([]TaskTag, len(tagIDs))
+	for i, id := range tagIDs {
+		links[i] = TaskTag{TaskID: taskID, TagID: id}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+// CreateTag 创建标签
+func CreateTag(tag *Tag) error {
+	return db.DB.Create(tag).Error
+}
+
+// RenameTag 修改标签名称
+func RenameTag(tag *Tag) error {
+	return db.DB.Model(tag).Where("user_id = ?", tag.UserID).Update("name", tag.Name).Error
+}
+
+// MergeTags 将标签 source 合并到 target，带有 source 的任务改为带有 target，然后删除 source
+func MergeTags(source, target *Tag) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Model(&TaskTag{}).Where("tag_id = ?", source.ID).Pluck("task_id", &taskIDs).Error; err != nil {
+			return err
+		}
+		for _, taskID := range taskIDs {
+			if err := addTaskTagsTx(tx, taskID, []uint{target.ID}); err != nil {
+				return err
+			}
+		}
+		return deleteTagTx(tx, source)
+	})
+}
+
+// DeleteTag 删除标签，并从所有任务上移除
+func DeleteTag(tag *Tag) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTagTx(tx, tag)
+	})
+}
+
+func deleteTagTx(tx *gorm.DB, tag *Tag) error {
+	if err := tx.Where("tag_id = ?", tag.ID).Delete(&TaskTag{}).Error; err != nil {
+		return err
+	}
+	result := tx.Where("user_id = ?", tag.UserID).Delete(&Tag{}, tag.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
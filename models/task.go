@@ -2,51 +2,188 @@ package models
 
 import (
 	"AITodo/db"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
+// ErrTaskNotFound 任务不存在或不属于当前用户
+// 两种情况统一返回该错误，避免泄露其他用户任务的存在性
+var ErrTaskNotFound = errors.New("任务不存在")
+
+// ErrVersionConflict 任务在读取后已被其他请求修改
+var ErrVersionConflict = errors.New("任务已被修改，请刷新后重试")
+
 type Task struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID      uint      `json:"user_id"`
-	Title       string    `gorm:"size:255;not null" json:"title" binding:"required"`
-	Category    string    `gorm:"size:100;default:'其他'" json:"category" binding:"required"`
-	Location    string    `gorm:"size:255" json:"location"`
-	Description string    `gorm:"type:text" json:"description"`
-	StartDate   time.Time `gorm:"index" json:"start_date" binding:"required"`
-	DueDate     time.Time `gorm:"index" json:"due_date" binding:"required"`
-	Status      string    `gorm:"size:50;default:'pending';index" json:"status"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID            uint              `gorm:"index" json:"user_id"`
+	Title             string            `gorm:"size:255;not null" json:"title" binding:"required"`
+	Category          string            `gorm:"size:100;default:'其他'" json:"category" binding:"required"`
+	Location          string            `gorm:"size:255" json:"location"`
+	Description       string            `gorm:"type:text" json:"description"`
+	StartDate         time.Time         `gorm:"index" json:"start_date" binding:"required"`
+	DueDate           time.Time         `gorm:"index" json:"due_date" binding:"required"`
+	Status            string            `gorm:"size:50;default:'pending';index" json:"status"`
+	Priority          string            `gorm:"size:2;default:'P2';index" json:"priority"`                        // 优先级 P0~P3
+	Important         *bool             `json:"important"`                                                        // 是否重要，为空时由优先级推断
+	Urgent            *bool             `json:"urgent"`                                                           // 是否紧急，为空时由优先级和截止时间推断
+	Version           uint              `gorm:"not null;default:1" json:"version"`                                // 乐观锁版本号，每次更新加一
+	Recurrence        string            `gorm:"type:text" json:"recurrence"`                                      // 重复规则（RFC 5545 的 RRULE/RDATE/EXDATE），以 StartDate 为 DTSTART
+	SeriesID          *uint             `gorm:"index" json:"series_id,omitempty"`                                 // 例外实例所属的重复任务ID
+	RecurrenceID      *time.Time        `json:"recurrence_id,omitempty"`                                          // 例外实例替换的原始发生时间
+	ParentID          *uint             `gorm:"index" json:"parent_id"`                                           // 父任务ID，为空表示顶层任务
+	ProjectID         *uint             `gorm:"index" json:"project_id"`                                          // 所属项目ID，为空表示不属于任何项目
+	EstimateMinutes   int               `gorm:"not null;default:0" json:"estimate_minutes"`                       // 预计用时（分钟），0 表示未估计
+	Rank              string            `gorm:"column:board_rank;size:255;not null;default:'';index" json:"rank"` // 看板中在所属状态列内的排序值，按字典序排列
+	Tags              []Tag             `gorm:"many2many:task_tags" json:"tags"`                                  // 标签，通过 SetTaskTagsTx 单独维护
+	Checklist         []ChecklistItem   `gorm:"foreignKey:TaskID" json:"checklist"`                               // 检查项，通过检查项接口单独维护
+	ChecklistProgress ChecklistProgress `gorm:"-" json:"checklist_progress"`                                      // 检查项完成进度，查询任务时计算
+	StartedAt         *time.Time        `json:"started_at"`                                                       // 第一次进入进行中的时间
+	CompletedAt       *time.Time        `gorm:"index" json:"completed_at"`                                        // 完成时间，重新打开后清空
+	CreatedAt         time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"` // 移入回收站的时间，同一次删除的任务取值相同
+}
+
+// OwnedBy 将查询限定在指定用户的任务范围内，所有对 tasks 表的读写都应通过该 scope
+func OwnedBy(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tasks.user_id = ?", userID)
+	}
 }
 
 // 数据库操作封装
-func GetAllTasks() (*[]Task, error) {
+func GetAllTasks(userID uint) (*[]Task, error) {
 	var tasks []Task
-	err := db.DB.Find(&tasks).Error
+	err := db.DB.Scopes(OwnedBy(userID)).Scopes(withTaskAssociations).Find(&tasks).Error
 	return &tasks, err
 }
 
-func GetTaskById(id uint) (*Task, error) {
+func GetTaskById(userID, id uint) (*Task, error) {
+	return GetTaskByIdTx(db.DB, userID, id)
+}
+
+// GetTaskByIdTx 在指定事务中获取任务，能读到事务中尚未提交的修改
+func GetTaskByIdTx(tx *gorm.DB, userID, id uint) (*Task, error) {
 	var task Task
-	err := db.DB.First(&task, id).Error
+	err := tx.Scopes(OwnedBy(userID)).Scopes(withTaskAssociations).First(&task, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &task, ErrTaskNotFound
+	}
 	return &task, err
 }
 
 func (t *Task) Create() error {
-	return db.DB.Create(t).Error
+	return t.CreateTx(db.DB)
 }
 
+// CreateTx 在指定事务中创建任务，关联 Tags 中已存在的标签并创建 Checklist 中的检查项
+func (t *Task) CreateTx(tx *gorm.DB) error {
+	t.Version = 1
+	if err := tx.Omit(clause.Associations).Create(t).Error; err != nil {
+		return err
+	}
+	if len(t.Tags) > 0 {
+		if err := SetTaskTagsTx(tx, t.ID, t.Tags); err != nil {
+			return err
+		}
+	}
+	// 复制检查项，避免修改复制来源任务（例如重复任务的系列）的检查项
+	t.Checklist = append([]ChecklistItem(nil), t.Checklist...)
+	if err := CreateChecklistItemsTx(tx, t, t.Checklist); err != nil {
+		return err
+	}
+	t.ChecklistProgress = NewChecklistProgress(t.Checklist)
+	return nil
+}
+
+// AfterFind 查询任务后根据预加载的检查项计算完成进度
+func (t *Task) AfterFind(tx *gorm.DB) error {
+	t.ChecklistProgress = NewChecklistProgress(t.Checklist)
+	return nil
+}
+
+// Update 保存任务的全部字段
 func (t *Task) Update() error {
-	return db.DB.Save(t).Error
+	return t.UpdateTx(db.DB)
+}
+
+// UpdateTx 在指定事务中保存任务的全部字段
+// 不使用 Save，避免记录不属于该用户时 gorm 回退为插入
+func (t *Task) UpdateTx(tx *gorm.DB) error {
+	return t.updateWithVersion(tx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("*").Omit("id", "user_id", "created_at", clause.Associations)
+	})
+}
+
+// UpdateColumns 只更新指定的列
+func (t *Task) UpdateColumns(columns ...string) error {
+	return t.UpdateColumnsTx(db.DB, columns...)
+}
+
+// UpdateColumnsTx 在指定事务中只更新指定的列
+func (t *Task) UpdateColumnsTx(tx *gorm.DB, columns ...string) error {
+	return t.updateWithVersion(tx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Select(append(columns, "version"))
+	})
+}
+
+// updateWithVersion 以读取时的版本号为条件更新，并将版本号加一
+// 版本号不匹配时说明任务已被其他请求修改，返回 ErrVersionConflict
+func (t *Task) updateWithVersion(tx *gorm.DB, selector func(*gorm.DB) *gorm.DB) error {
+	current := t.Version
+	t.Version++
+	result := tx.Model(t).Scopes(OwnedBy(t.UserID), selector).Where("tasks.version = ?", current).Updates(t)
+	if result.Error != nil {
+		t.Version = current
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		t.Version = current
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteTask 将任务移入回收站，重复任务的例外实例一并移入
+func DeleteTask(userID, id uint) error {
+	return DeleteTasks(userID, []uint{id})
+}
+
+// DeleteTasks 在同一事务中将多个任务及其例外实例移入回收站
+func DeleteTasks(userID uint, ids []uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return DeleteTasksTx(tx, userID, ids)
+	})
+}
+
+// DeleteTasksTx 在指定事务中将多个任务及其例外实例移入回收站，任一任务不存在时返回 ErrTaskNotFound
+// 依赖关系保留，以便恢复后仍然有效
+func DeleteTasksTx(tx *gorm.DB, userID uint, ids []uint) error {
+	now := time.Now()
+	result := tx.Model(&Task{}).Scopes(OwnedBy(userID)).Where("tasks.id IN ?", ids).Updates(trashColumns(now))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < int64(len(ids)) {
+		return ErrTaskNotFound
+	}
+	return tx.Model(&Task{}).Scopes(OwnedBy(userID)).Where("tasks.series_id IN ?", ids).
+		Updates(trashColumns(now)).Error
 }
 
-func DeleteTask(id uint) error {
-	return db.DB.Delete(&Task{}, id).Error
+// trashColumns 移入回收站时更新的列，使用同一时间标记同一次删除的全部任务
+func trashColumns(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"deleted_at": now,
+		"version":    gorm.Expr("version + 1"),
+	}
 }
 
-func CountAllTasks() (int64, error) {
+func CountAllTasks(userID uint) (int64, error) {
 	var count int64
-	err := db.DB.Table("tasks").Count(&count).Error
+	err := db.DB.Model(&Task{}).Scopes(OwnedBy(userID)).Count(&count).Error
 	return count, err
 }
 
@@ -55,8 +192,8 @@ type Result struct {
 	Total    int
 }
 
-func CountTaskByCategory() *[]Result {
-	var rets *[]Result
-	db.DB.Table("tasks").Select("category,sum(category)").Group("category").Scan(rets)
-	return rets
+func CountTaskByCategory(userID uint) *[]Result {
+	var rets []Result
+	db.DB.Model(&Task{}).Scopes(OwnedBy(userID)).Select("category, count(*) AS total").Group("category").Scan(&rets)
+	return &rets
 }
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"AITodo/db"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 审计记录的操作者类型
+const (
+	ActorUser   = "user"   // 用户通过接口操作
+	ActorAI     = "ai"     // AI 助手的工具调用
+	ActorSystem = "system" // 后台任务
+)
+
+// 审计记录的操作类型
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditActor 修改任务的操作者
+// 用户的 ID 为用户ID，AI 的 ID 为一次对话的会话ID，后台任务的 ID 为任务名称
+type AuditActor struct {
+	Type string
+	ID   string
+}
+
+// UserActor 用户通过接口操作
+func UserActor(userID uint) AuditActor {
+	return AuditActor{Type: ActorUser, ID: strconv.FormatUint(uint64(userID), 10)}
+}
+
+// AIActor AI 助手在一次会话中的操作，同一会话的修改可以一起撤销
+func AIActor(sessionID string) AuditActor {
+	return AuditActor{Type: ActorAI, ID: sessionID}
+}
+
+// SystemActor 后台任务的操作
+func SystemActor(job string) AuditActor {
+	return AuditActor{Type: ActorSystem, ID: job}
+}
+
+// TaskAudit 任务审计记录，保存修改前后的任务快照和字段差异
+type TaskAudit struct {
+	ID        uint            `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint            `gorm:"index" json:"user_id"`
+	TaskID    uint            `gorm:"index" json:"task_id"`
+	Action    string          `gorm:"size:20" json:"action"`
+	ActorType string          `gorm:"size:20;index:idx_audit_actor" json:"actor_type"`
+	ActorID   string          `gorm:"size:64;index:idx_audit_actor" json:"actor_id"`
+	Before    json.RawMessage `gorm:"type:json" json:"before"`           // 修改前的任务，创建时为空
+	After     json.RawMessage `gorm:"type:json" json:"after"`            // 修改后的任务
+	Changes   json.RawMessage `gorm:"type:json" json:"changes"`          // 发生变化的字段及前后取值
+	UndoOf    *uint           `json:"undo_of,omitempty"`                 // 该记录是对哪条记录的撤销
+	CascadeOf *uint           `gorm:"index" json:"cascade_of,omitempty"` // 该记录由哪条记录连带产生，随其一起撤销
+	UndoneAt  *time.Time      `json:"undone_at,omitempty"`               // 被撤销的时间
+	CreatedAt time.Time       `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// undoableActions 可以撤销的操作
+var undoableActions = []string{AuditCreate, AuditUpdate, AuditDelete, AuditRestore}
+
+// CreateAudit 在指定事务中写入审计记录
+func CreateAudit(tx *gorm.DB, audit *TaskAudit) error {
+	return tx.Create(audit).Error
+}
+
+// ListTaskAudits 获取任务的审计记录，最近的在前
+func ListTaskAudits(userID, taskID uint) ([]TaskAudit, error) {
+	var audits []TaskAudit
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).
+		Order("id DESC").
+		Find(&audits).Error
+	return audits, err
+}
+
+// GetLastUndoableAudit 获取任务最近一条尚未撤销的修改，撤销操作本身不能再撤销
+// 连带产生的修改只能随产生它的记录一起撤销
+func GetLastUndoableAudit(userID, taskID uint) (*TaskAudit, error) {
+	var audit TaskAudit
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).
+		Where("undone_at IS NULL AND undo_of IS NULL AND cascade_of IS NULL AND action IN ?", undoableActions).
+		Order("id DESC").
+		First(&audit).Error
+	return &audit, err
+}
+
+// ListLastAIBatch 获取最近一次仍有未撤销修改的 AI 会话中尚未撤销的修改，最近的在前
+// 最近的会话已经撤销后再次调用会撤销更早的会话
+func ListLastAIBatch(userID uint) ([]TaskAudit, error) {
+	var last TaskAudit
+	err := db.DB.Where("user_id = ? AND actor_type = ?", userID, ActorAI).
+		Where("undone_at IS NULL AND undo_of IS NULL AND cascade_of IS NULL AND action IN ?", undoableActions).
+		Order("id DESC").
+		First(&last).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var audits []TaskAudit
+	err = db.DB.Where("user_id = ? AND actor_type = ? AND actor_id = ?", userID, ActorAI, last.ActorID).
+		Where("undone_at IS NULL AND undo_of IS NULL AND cascade_of IS NULL AND action IN ?", undoableActions).
+		Order("id DESC").
+		Find(&audits).Error
+	return audits, err
+}
+
+// ListCascadedAuditsTx 在指定事务中获取由 id 连带产生且尚未撤销的记录，最近的在前
+func ListCascadedAuditsTx(tx *gorm.DB, id uint) ([]TaskAudit, error) {
+	var audits []TaskAudit
+	err := tx.Where("cascade_of = ? AND undone_at IS NULL", id).
+		Order("id DESC").
+		Find(&audits).Error
+	return audits, err
+}
+
+// MarkAuditUndone 在指定事务中将审计记录标记为已撤销
+func MarkAuditUndone(tx *gorm.DB, id uint, at time.Time) error {
+	result := tx.Model(&TaskAudit{}).Where("id = ? AND undone_at IS NULL", id).Update("undone_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
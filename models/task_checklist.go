@@ -0,0 +1,149 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxChecklistItems 单个任务最多的检查项数量
+const MaxChecklistItems = 100
+
+// ErrChecklistItemNotFound 检查项不存在或不属于该任务
+var ErrChecklistItemNotFound = errors.New("检查项不存在")
+
+// ChecklistItem 任务内的检查项，按 Position 从小到大排列
+type ChecklistItem struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    uint      `gorm:"index" json:"task_id"`
+	UserID    uint      `gorm:"index" json:"-"`
+	Content   string    `gorm:"size:255;not null" json:"content"`
+	Done      bool      `gorm:"not null;default:false" json:"done"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ChecklistProgress 检查项完成进度
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// NewChecklistProgress 统计检查项的完成进度
+func NewChecklistProgress(items []ChecklistItem) ChecklistProgress {
+	progress := ChecklistProgress{Total: len(items)}
+	for _, item := range items {
+		if item.Done {
+			progress.Done++
+		}
+	}
+	return progress
+}
+
+// withTaskAssociations 预加载任务的标签和按顺序排列的检查项
+func withTaskAssociations(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Tags").Preload("Checklist", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position").Order("id")
+	})
+}
+
+// ListChecklistItemsTx 在指定事务中按顺序获取任务的检查项
+func ListChecklistItemsTx(tx *gorm.DB, taskID uint) ([]ChecklistItem, error) {
+	var items []ChecklistItem
+	err := tx.Where("task_id = ?", taskID).Order("position").Order("id").Find(&items).Error
+	return items, err
+}
+
+// CreateChecklistItemsTx 在指定事务中将检查项依次追加到任务末尾
+func CreateChecklistItemsTx(tx *gorm.DB, task *Task, items []ChecklistItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	var maxPosition *int
+	if err := tx.Model(&ChecklistItem{}).Where("task_id = ?", task.ID).
+		Select("MAX(position)").Scan(&maxPosition).Error; err != nil {
+		return err
+	}
+	next := 0
+	if maxPosition != nil {
+		next = *maxPosition + 1
+	}
+	for i := range items {
+		items[i].ID = 0
+		items[i].TaskID = task.ID
+		items[i].UserID = task.UserID
+		items[i].Position = next + i
+	}
+	return tx.Create(&items).Error
+}
+
+// ReplaceChecklistItemsTx 在指定事务中删除任务的全部检查项，并按顺序重新创建 items
+func ReplaceChecklistItemsTx(tx *gorm.DB, task *Task, items []ChecklistItem) error {
+	if err := tx.Where("task_id = ?", task.ID).Delete(&ChecklistItem{}).Error; err != nil {
+		return err
+	}
+	return CreateChecklistItemsTx(tx, task, items)
+}
+
+// GetChecklistItemTx 在指定事务中获取任务的检查项
+func GetChecklistItemTx(tx *gorm.DB, taskID, id uint) (*ChecklistItem, error) {
+	var item ChecklistItem
+	err := tx.Where("task_id = ?", taskID).First(&item, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChecklistItemNotFound
+	}
+	return &item, err
+}
+
+// UpdateColumnsTx 在指定事务中只更新检查项的指定列
+func (i *ChecklistItem) UpdateColumnsTx(tx *gorm.DB, columns ...string) error {
+	return tx.Model(i).Where("task_id = ?", i.TaskID).Select(columns).Updates(i).Error
+}
+
+// ReorderChecklistItemsTx 在指定事务中按 ids 的顺序重新设置检查项的位置，ids 必须包含任务的全部检查项
+func ReorderChecklistItemsTx(tx *gorm.DB, taskID uint, ids []uint) error {
+	var count int64
+	if err := tx.Model(&ChecklistItem{}).Where("task_id = ?", taskID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return ErrChecklistItemNotFound
+	}
+	for i, id := range ids {
+		result := tx.Model(&ChecklistItem{}).Where("task_id = ? AND id = ?", taskID, id).Update("position", i)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChecklistItemNotFound
+		}
+	}
+	return nil
+}
+
+// DeleteChecklistItemTx 在指定事务中删除检查项
+func DeleteChecklistItemTx(tx *gorm.DB, taskID, id uint) error {
+	result := tx.Where("task_id = ?", taskID).Delete(&ChecklistItem{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrChecklistItemNotFound
+	}
+	return nil
+}
+
+// CountChecklistItems 统计任务的检查项数量
+func CountChecklistItems(tx *gorm.DB, taskID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&ChecklistItem{}).Where("task_id = ?", taskID).Count(&count).Error
+	return count, err
+}
+
+// ListChecklistItems 按顺序获取任务的检查项
+func ListChecklistItems(taskID uint) ([]ChecklistItem, error) {
+	return ListChecklistItemsTx(db.DB, taskID)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxCommentLength 评论内容的最大长度（字节）
+const MaxCommentLength = 10000
+
+// ErrCommentNotFound 评论不存在或不属于该任务
+var ErrCommentNotFound = errors.New("评论不存在")
+
+// TaskComment 任务下的评论，Body 为 Markdown 格式的原文，由客户端负责渲染
+type TaskComment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    uint      `gorm:"index" json:"task_id"`
+	UserID    uint      `gorm:"index" json:"author_id"` // 评论作者
+	Author    string    `gorm:"->;-:migration" json:"author"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// withCommentAuthor 查询评论时带出作者的用户名
+func withCommentAuthor(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&TaskComment{}).
+		Select("task_comments.*, users.user_name AS author").
+		Joins("LEFT JOIN users ON users.id = task_comments.user_id")
+}
+
+// ListComments 按时间顺序获取任务的评论
+func ListComments(taskID uint) ([]TaskComment, error) {
+	var comments []TaskComment
+	err := db.DB.Scopes(withCommentAuthor).
+		Where("task_comments.task_id = ?", taskID).
+		Order("task_comments.created_at, task_comments.id").
+		Find(&comments).Error
+	return comments, err
+}
+
+// GetCommentTx 在指定事务中获取任务的评论
+func GetCommentTx(tx *gorm.DB, taskID, id uint) (*TaskComment, error) {
+	var comment TaskComment
+	err := tx.Scopes(withCommentAuthor).
+		Where("task_comments.task_id = ? AND task_comments.id = ?", taskID, id).
+		First(&comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	return &comment, err
+}
+
+// CreateCommentTx 在指定事务中创建评论
+func CreateCommentTx(tx *gorm.DB, comment *TaskComment) error {
+	return tx.Omit("Author").Create(comment).Error
+}
+
+// UpdateBodyTx 在指定事务中修改评论内容
+func (c *TaskComment) UpdateBodyTx(tx *gorm.DB) error {
+	return tx.Model(c).Select("body").Updates(c).Error
+}
+
+// DeleteCommentTx 在指定事务中删除评论
+func DeleteCommentTx(tx *gorm.DB, taskID, id uint) error {
+	result := tx.Where("task_id = ?", taskID).Delete(&TaskComment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
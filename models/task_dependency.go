@@ -0,0 +1,121 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDependencyNotFound 依赖关系不存在
+var ErrDependencyNotFound = errors.New("依赖关系不存在")
+
+// TaskDependency 任务依赖关系，TaskID 被 BlockedByID 阻塞
+type TaskDependency struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	TaskID      uint      `gorm:"uniqueIndex:idx_task_blocked_by" json:"task_id"`
+	BlockedByID uint      `gorm:"uniqueIndex:idx_task_blocked_by;index" json:"blocked_by_id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (d *TaskDependency) Create() error {
+	return d.CreateTx(db.DB)
+}
+
+// CreateTx 在指定事务中创建依赖关系
+func (d *TaskDependency) CreateTx(tx *gorm.DB) error {
+	return tx.Create(d).Error
+}
+
+// ListDependencies 获取当前用户的全部依赖关系
+func ListDependencies(userID uint) ([]TaskDependency, error) {
+	return ListDependenciesTx(db.DB, userID)
+}
+
+// ListDependenciesTx 在指定事务中获取当前用户的全部依赖关系
+func ListDependenciesTx(tx *gorm.DB, userID uint) ([]TaskDependency, error) {
+	var deps []TaskDependency
+	err := tx.Where("user_id = ?", userID).Find(&deps).Error
+	return deps, err
+}
+
+// ListBlockers 获取阻塞指定任务的前置任务
+func ListBlockers(userID, taskID uint) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Joins("JOIN task_dependencies ON task_dependencies.blocked_by_id = tasks.id").
+		Where("task_dependencies.task_id = ?", taskID).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ListDependents 获取被指定任务阻塞的后续任务
+func ListDependents(userID, taskID uint) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Joins("JOIN task_dependencies ON task_dependencies.task_id = tasks.id").
+		Where("task_dependencies.blocked_by_id = ?", taskID).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// CountOpenBlockers 统计指定任务尚未完成的前置任务数量
+func CountOpenBlockers(userID, taskID uint) (int64, error) {
+	var count int64
+	err := db.DB.Model(&Task{}).Scopes(OwnedBy(userID)).
+		Joins("JOIN task_dependencies ON task_dependencies.blocked_by_id = tasks.id").
+		Where("task_dependencies.task_id = ? AND tasks.status <> ?", taskID, TaskStatusCompleted).
+		Count(&count).Error
+	return count, err
+}
+
+// ListActionableTasks 获取未完成且所有前置任务均已完成的任务
+func ListActionableTasks(userID uint) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Where("tasks.status <> ?", TaskStatusCompleted).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM task_dependencies
+			JOIN tasks AS blockers ON blockers.id = task_dependencies.blocked_by_id
+			WHERE task_dependencies.task_id = tasks.id AND blockers.status <> ? AND blockers.deleted_at IS NULL)`,
+			TaskStatusCompleted).
+		Order("tasks.due_date, tasks.id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ListTasksByIDs 获取当前用户指定ID的任务
+func ListTasksByIDs(userID uint, ids []uint) ([]Task, error) {
+	return ListTasksByIDsTx(db.DB, userID, ids)
+}
+
+// ListTasksByIDsTx 在指定事务中获取当前用户指定ID的任务
+func ListTasksByIDsTx(tx *gorm.DB, userID uint, ids []uint) ([]Task, error) {
+	var tasks []Task
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	err := tx.Scopes(OwnedBy(userID)).Where("tasks.id IN ?", ids).Find(&tasks).Error
+	return tasks, err
+}
+
+// DeleteDependency 删除依赖关系
+func DeleteDependency(userID, taskID, blockedByID uint) error {
+	result := db.DB.Where("user_id = ? AND task_id = ? AND blocked_by_id = ?", userID, taskID, blockedByID).
+		Delete(&TaskDependency{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+// deleteDependenciesOf 删除与指定任务相关的全部依赖关系
+func deleteDependenciesOf(tx *gorm.DB, userID uint, ids []uint) error {
+	return tx.Where("user_id = ? AND (task_id IN ? OR blocked_by_id IN ?)", userID, ids, ids).
+		Delete(&TaskDependency{}).Error
+}
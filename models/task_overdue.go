@@ -0,0 +1,49 @@
+package models
+
+import (
+	"AITodo/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OverdueTask 逾期未完成的任务及其所属用户的处理策略
+type OverdueTask struct {
+	Task
+	OverduePolicy string
+}
+
+// TaskOverdueRecord 后台扫描对逾期任务所做的处理记录
+type TaskOverdueRecord struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      uint      `gorm:"index" json:"task_id"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	Policy      string    `gorm:"size:20" json:"policy"`
+	FromStatus  string    `gorm:"size:50" json:"from_status"`
+	ToStatus    string    `gorm:"size:50" json:"to_status"`
+	FromDueDate time.Time `json:"from_due_date"`
+	ToDueDate   time.Time `json:"to_due_date"`
+	SweptAt     time.Time `gorm:"index" json:"swept_at"`
+}
+
+// ListOverdueTasks 按 id 顺序获取 afterID 之后已逾期且需要处理的任务
+// 重复任务的系列本身不参与处理，未设置截止时间的任务也不算逾期
+func ListOverdueTasks(now time.Time, afterID uint, limit int) ([]OverdueTask, error) {
+	var tasks []OverdueTask
+	err := db.DB.Model(&Task{}).
+		Select("tasks.*, users.overdue_policy").
+		Joins("JOIN users ON users.id = tasks.user_id").
+		Where("tasks.id > ?", afterID).
+		Where("tasks.status IN ?", []string{TaskStatusPending, TaskStatusInProgress}).
+		Where("tasks.recurrence = '' AND tasks.due_date > ? AND tasks.due_date < ?", time.Time{}, now).
+		Where("users.overdue_policy IN ?", []string{OverduePolicyFail, OverduePolicyRollover}).
+		Order("tasks.id").
+		Limit(limit).
+		Scan(&tasks).Error
+	return tasks, err
+}
+
+// CreateOverdueRecord 在指定事务中写入逾期处理记录
+func CreateOverdueRecord(tx *gorm.DB, record *TaskOverdueRecord) error {
+	return tx.Create(record).Error
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"AITodo/db"
+)
+
+// 任务优先级，P0 最高
+const (
+	PriorityP0 = "P0" // 紧急且重要
+	PriorityP1 = "P1" // 重要
+	PriorityP2 = "P2" // 普通
+	PriorityP3 = "P3" // 不着急
+)
+
+// DefaultPriority 未指定优先级时使用的优先级
+const DefaultPriority = PriorityP2
+
+// IsValidPriority 判断是否为合法的优先级
+func IsValidPriority(priority string) bool {
+	switch priority {
+	case PriorityP0, PriorityP1, PriorityP2, PriorityP3:
+		return true
+	}
+	return false
+}
+
+// ListOpenTasks 获取用户未完成的任务（待办和进行中），按截止时间排序
+func ListOpenTasks(userID uint) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Where("tasks.status IN ?", []string{TaskStatusPending, TaskStatusInProgress}).
+		Order("tasks.due_date").Order("tasks.id").
+		Scopes(withTaskAssociations).
+		Find(&tasks).Error
+	return tasks, err
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"AITodo/db"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaskFilter 任务列表的筛选条件，零值字段表示不筛选
+type TaskFilter struct {
+	Statuses   []string
+	Priorities []string
+	Categories []string
+	ProjectIDs []uint // 所属项目
+	NoProject  bool   // 包含不属于任何项目的任务，与 ProjectIDs 为或的关系
+	Location   string
+	StartFrom  *time.Time
+	StartTo    *time.Time
+	DueFrom    *time.Time
+	DueTo      *time.Time
+	Overdue    bool
+	Keyword    string
+	Tags       *TagExpr // 标签布尔查询
+}
+
+// TaskSort 排序字段及方向
+type TaskSort struct {
+	Field string
+	Desc  bool
+}
+
+// TaskSortFields 允许排序的字段，值表示字段是否为时间类型
+// 排序字段会直接拼接进 SQL，必须经过该白名单校验
+var TaskSortFields = map[string]bool{
+	"id":         false,
+	"title":      false,
+	"status":     false,
+	"priority":   false,
+	"category":   false,
+	"location":   false,
+	"start_date": true,
+	"due_date":   true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Scope 将筛选条件转换为 gorm scope
+func (f TaskFilter) Scope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if len(f.Statuses) > 0 {
+			tx = tx.Where("tasks.status IN ?", f.Statuses)
+		}
+		if len(f.Priorities) > 0 {
+			tx = tx.Where("tasks.priority IN ?", f.Priorities)
+		}
+		if len(f.Categories) > 0 {
+			tx = tx.Where("tasks.category IN ?", f.Categories)
+		}
+		switch {
+		case len(f.ProjectIDs) > 0 && f.NoProject:
+			tx = tx.Where("(tasks.project_id IN ? OR tasks.project_id IS NULL)", f.ProjectIDs)
+		case len(f.ProjectIDs) > 0:
+			tx = tx.Where("tasks.project_id IN ?", f.ProjectIDs)
+		case f.NoProject:
+			tx = tx.Where("tasks.project_id IS NULL")
+		}
+		if f.Location != "" {
+			tx = tx.Where("tasks.location LIKE ?", "%"+escapeLike(f.Location)+"%")
+		}
+		if f.StartFrom != nil {
+			tx = tx.Where("tasks.start_date >= ?", *f.StartFrom)
+		}
+		if f.StartTo != nil {
+			tx = tx.Where("tasks.start_date <= ?", *f.StartTo)
+		}
+		if f.DueFrom != nil {
+			tx = tx.Where("tasks.due_date >= ?", *f.DueFrom)
+		}
+		if f.DueTo != nil {
+			tx = tx.Where("tasks.due_date <= ?", *f.DueTo)
+		}
+		if f.Overdue {
+			tx = tx.Where("tasks.due_date < ? AND tasks.status <> ?", time.Now(), TaskStatusCompleted)
+		}
+		if f.Tags != nil {
+			sql, args := f.Tags.SQL()
+			tx = tx.Where("("+sql+")", args...)
+		}
+		if f.Keyword != "" {
+			like := "%" + escapeLike(f.Keyword) + "%"
+			tx = tx.Where("(tasks.title LIKE ? OR tasks.description LIKE ?)", like, like)
+		}
+		return tx
+	}
+}
+
+// CountTasks 统计满足筛选条件的任务总数
+func CountTasks(userID uint, filter TaskFilter) (int64, error) {
+	var count int64
+	err := db.DB.Model(&Task{}).Scopes(OwnedBy(userID), filter.Scope()).Count(&count).Error
+	return count, err
+}
+
+// ListTasks 按筛选条件和排序进行游标分页查询
+// sorts 的最后一项必须是唯一字段（id），after 为上一页最后一条记录在各排序字段上的取值，为空表示第一页
+func ListTasks(userID uint, filter TaskFilter, sorts []TaskSort, after []interface{}, limit int) ([]Task, error) {
+	query := db.DB.Model(&Task{}).Scopes(OwnedBy(userID), filter.Scope())
+
+	if len(after) == len(sorts) {
+		query = query.Where(keysetCondition(sorts, after))
+	}
+	for _, s := range sorts {
+		order := "tasks." + s.Field
+		if s.Desc {
+			order += " DESC"
+		}
+		query = query.Order(order)
+	}
+
+	var tasks []Task
+	err := query.Scopes(withTaskAssociations).Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// keysetCondition 构造 (f1 > v1) OR (f1 = v1 AND f2 > v2) ... 形式的游标条件
+func keysetCondition(sorts []TaskSort, after []interface{}) *gorm.DB {
+	cond := db.DB.Session(&gorm.Session{NewDB: true})
+	for i, s := range sorts {
+		op := ">"
+		if s.Desc {
+			op = "<"
+		}
+		branch := db.DB.Session(&gorm.Session{NewDB: true})
+		for j := 0; j < i; j++ {
+			branch = branch.Where("tasks."+sorts[j].Field+" = ?", after[j])
+		}
+		branch = branch.Where("tasks."+s.Field+" "+op+" ?", after[i])
+		if i == 0 {
+			cond = cond.Where(branch)
+		} else {
+			cond = cond.Or(branch)
+		}
+	}
+	return cond
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
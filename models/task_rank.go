@@ -0,0 +1,211 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 看板排序值由 0-9a-z 组成，按字符串字典序排列，且不以 0 结尾，
+// 因此任意两个不同的排序值之间总能找到新的排序值，移动任务时只需修改被移动的任务
+const (
+	rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	rankBase   = len(rankDigits)
+	rankWidth  = 6                              // 追加到列首或列尾时使用的定长部分
+	rankStep   = rankBase * rankBase * rankBase // 追加时定长部分的间隔
+	rankMax    = rankStep * rankStep            // 定长部分的取值上限，即 36^6
+)
+
+// MaxRankLength 排序值的最大长度，超过时重新分配所在列的排序值
+const MaxRankLength = 200
+
+// ErrRankConflict 两个相邻任务的排序值相同或顺序颠倒，需要重新分配该列的排序值
+var ErrRankConflict = errors.New("排序值冲突")
+
+// BoardStatuses 看板的列，按显示顺序排列
+var BoardStatuses = []string{TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed}
+
+// RankBetween 返回严格位于 prev 与 next 之间的排序值，prev 为空表示列首，next 为空表示列尾
+func RankBetween(prev, next string) (string, error) {
+	switch {
+	case prev == "" && next == "":
+		return formatRank(rankMax / 2), nil
+	case next == "":
+		if head := parseRankHead(prev); head+rankStep < rankMax {
+			return formatRank(head + rankStep), nil
+		}
+	case prev == "":
+		if head := parseRankHead(next); head > rankStep {
+			return formatRank(head - rankStep), nil
+		}
+	case prev >= next:
+		return "", ErrRankConflict
+	}
+	return rankMidpoint(prev, next), nil
+}
+
+// rankMidpoint 返回 a 与 b 之间的排序值，b 为空表示没有上界，调用方保证 a < b
+func rankMidpoint(a, b string) string {
+	// 跳过公共前缀，a 较短的部分按 0 补齐
+	if b != "" {
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == strings.IndexByte(rankDigits, b[n]) {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+
+	lo, hi := rankDigitAt(a, 0), rankBase
+	if b != "" {
+		hi = strings.IndexByte(rankDigits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(rankDigits[(lo+hi+1)/2])
+	}
+	// 首位相邻：b 还有后续字符时取 b 的首位即可，否则保留 a 的首位继续向后查找
+	if b != "" && len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[lo]) + rankMidpoint(rest, "")
+}
+
+func rankDigitAt(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, s[i])
+}
+
+// parseRankHead 读取排序值的定长部分
+func parseRankHead(rank string) int {
+	head := 0
+	for i := 0; i < rankWidth; i++ {
+		head = head*rankBase + rankDigitAt(rank, i)
+	}
+	return head
+}
+
+// formatRank 将定长部分转换为排序值，去掉末尾的 0
+func formatRank(head int) string {
+	buf := make([]byte, rankWidth)
+	for i := rankWidth - 1; i >= 0; i-- {
+		buf[i] = rankDigits[head%rankBase]
+		head /= rankBase
+	}
+	return strings.TrimRight(string(buf), "0")
+}
+
+// boardColumn 用户某一状态列中的任务
+func boardColumn(tx *gorm.DB, userID uint, status string) *gorm.DB {
+	return tx.Model(&Task{}).Scopes(OwnedBy(userID)).Where("tasks.status = ?", status)
+}
+
+// boardOrder 看板中任务的顺序，尚未分配排序值的任务排在最后
+func boardOrder(tx *gorm.DB) *gorm.DB {
+	return tx.Order("tasks.board_rank = ''").Order("tasks.board_rank").Order("tasks.id")
+}
+
+// LastRankTx 在指定事务中获取状态列中最大的排序值，列为空时返回空字符串
+func LastRankTx(tx *gorm.DB, userID uint, status string) (string, error) {
+	var ranks []string
+	err := boardColumn(tx, userID, status).Order("tasks.board_rank DESC").Limit(1).
+		Pluck("tasks.board_rank", &ranks).Error
+	if err != nil || len(ranks) == 0 {
+		return "", err
+	}
+	return ranks[0], nil
+}
+
+// NeighborRankTx 在指定事务中获取状态列中紧挨着 rank 的排序值，after 为 true 时取后一个，否则取前一个
+// excludeID 为正在移动的任务，不参与比较；没有相邻任务时返回空字符串
+func NeighborRankTx(tx *gorm.DB, userID uint, status, rank string, after bool, excludeID uint) (string, error) {
+	query := boardColumn(tx, userID, status).Where("tasks.id <> ? AND tasks.board_rank <> ''", excludeID)
+	if after {
+		query = query.Where("tasks.board_rank > ?", rank).Order("tasks.board_rank")
+	} else {
+		query = query.Where("tasks.board_rank < ?", rank).Order("tasks.board_rank DESC")
+	}
+	var ranks []string
+	if err := query.Limit(1).Pluck("tasks.board_rank", &ranks).Error; err != nil || len(ranks) == 0 {
+		return "", err
+	}
+	return ranks[0], nil
+}
+
+// RebalanceRanksTx 在指定事务中按当前顺序为状态列中的全部任务重新分配均匀分布的排序值，
+// 只在相邻任务的排序值冲突时使用，不修改任务的版本号
+func RebalanceRanksTx(tx *gorm.DB, userID uint, status string) error {
+	var ids []uint
+	if err := boardColumn(tx, userID, status).Scopes(boardOrder).Pluck("tasks.id", &ids).Error; err != nil {
+		return err
+	}
+	step := rankMax / (len(ids) + 1)
+	for i, id := range ids {
+		err := tx.Model(&Task{}).Where("id = ?", id).UpdateColumn("board_rank", formatRank((i+1)*step)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillTaskRanks 为还没有排序值的任务分配排序值，按截止时间排在所在列的末尾，用于升级后的数据迁移
+func BackfillTaskRanks() error {
+	type column struct {
+		UserID uint
+		Status string
+	}
+	var columns []column
+	err := db.DB.Unscoped().Model(&Task{}).Where("board_rank = ''").
+		Distinct("user_id", "status").Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range columns {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			last, err := LastRankTx(tx.Unscoped(), c.UserID, c.Status)
+			if err != nil {
+				return err
+			}
+			var ids []uint
+			err = tx.Unscoped().Model(&Task{}).Where("user_id = ? AND status = ? AND board_rank = ''", c.UserID, c.Status).
+				Order("due_date").Order("id").Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if last, err = RankBetween(last, ""); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&Task{}).Where("id = ?", id).UpdateColumn("board_rank", last).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBoardColumn 按看板顺序获取状态列中满足筛选条件的任务，最多 limit 个
+func ListBoardColumn(userID uint, filter TaskFilter, status string, limit int) ([]Task, error) {
+	var tasks []Task
+	err := boardColumn(db.DB, userID, status).Scopes(filter.Scope(), boardOrder, withTaskAssociations).
+		Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
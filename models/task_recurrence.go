@@ -0,0 +1,47 @@
+package models
+
+import (
+	"AITodo/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListRecurringTasks 获取在 end 之前开始的所有重复任务
+func ListRecurringTasks(userID uint, end time.Time) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Where("tasks.recurrence <> '' AND tasks.start_date <= ?", end).
+		Scopes(withTaskAssociations).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ListSingleTasksBetween 获取与 [start, end] 有交集的非重复任务（包括重复任务的例外实例）
+func ListSingleTasksBetween(userID uint, start, end time.Time) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Scopes(OwnedBy(userID)).
+		Where("(tasks.recurrence = '' OR tasks.recurrence IS NULL)").
+		Where("tasks.start_date <= ? AND tasks.due_date >= ?", end, start).
+		Order("tasks.start_date").
+		Scopes(withTaskAssociations).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// MoveExceptionsTx 将重复任务在 at 及之后的例外实例归属到拆分出的新系列 tailID
+func MoveExceptionsTx(tx *gorm.DB, head *Task, tailID uint, at time.Time) error {
+	return tx.Model(&Task{}).Scopes(OwnedBy(head.UserID)).
+		Where("series_id = ? AND recurrence_id >= ?", head.ID, at).
+		Update("series_id", tailID).Error
+}
+
+// TruncateSeriesTx 在指定事务中将重复任务在 at 之前截止，并将 at 之后的例外实例移入回收站
+func TruncateSeriesTx(tx *gorm.DB, series *Task, at time.Time) error {
+	if err := series.UpdateColumnsTx(tx, "recurrence"); err != nil {
+		return err
+	}
+	return tx.Model(&Task{}).Scopes(OwnedBy(series.UserID)).
+		Where("series_id = ? AND recurrence_id >= ?", series.ID, at).
+		Updates(trashColumns(time.Now())).Error
+}
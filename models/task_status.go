@@ -0,0 +1,67 @@
+package models
+
+import (
+	"AITodo/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 任务状态
+const (
+	TaskStatusPending    = "pending"     // 待办
+	TaskStatusInProgress = "in_progress" // 进行中
+	TaskStatusCompleted  = "completed"   // 已完成
+	TaskStatusFailed     = "failed"      // 已逾期失败
+)
+
+// taskTransitions 允许的状态流转，已完成的任务只能重新打开，不能直接标记为失败
+var taskTransitions = map[string][]string{
+	TaskStatusPending:    {TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed},
+	TaskStatusInProgress: {TaskStatusPending, TaskStatusCompleted, TaskStatusFailed},
+	TaskStatusCompleted:  {TaskStatusPending, TaskStatusInProgress},
+	TaskStatusFailed:     {TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted},
+}
+
+// IsValidTaskStatus 判断是否为合法的任务状态
+func IsValidTaskStatus(status string) bool {
+	_, ok := taskTransitions[status]
+	return ok
+}
+
+// CanTransition 判断任务状态能否从 from 流转到 to，状态不变视为允许
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range taskTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskStatusHistory 任务状态流转记录
+type TaskStatusHistory struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID     uint      `gorm:"index" json:"task_id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	FromStatus string    `gorm:"size:50" json:"from_status"` // 创建任务时为空
+	ToStatus   string    `gorm:"size:50" json:"to_status"`
+	ChangedAt  time.Time `gorm:"index" json:"changed_at"`
+}
+
+// CreateStatusHistory 在指定事务中写入状态流转记录
+func CreateStatusHistory(tx *gorm.DB, history *TaskStatusHistory) error {
+	return tx.Create(history).Error
+}
+
+// ListStatusHistory 获取任务的状态流转记录，按时间先后排列
+func ListStatusHistory(userID, taskID uint) ([]TaskStatusHistory, error) {
+	var histories []TaskStatusHistory
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).
+		Order("changed_at, id").
+		Find(&histories).Error
+	return histories, err
+}
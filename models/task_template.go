@@ -0,0 +1,121 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTemplateNotFound 模板不存在或不属于当前用户
+var ErrTemplateNotFound = errors.New("模板不存在")
+
+// TaskTemplate 任务模板，实例化时按模板中的任务依次创建任务
+type TaskTemplate struct {
+	ID          uint               `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint               `gorm:"not null;uniqueIndex:idx_template_user_name" json:"user_id"`
+	Name        string             `gorm:"size:100;not null;uniqueIndex:idx_template_user_name" json:"name"`
+	Description string             `gorm:"type:text" json:"description"`
+	ProjectName string             `gorm:"size:100" json:"project_name"` // 实例化出的任务所属项目，不存在时自动创建，为空表示不属于任何项目
+	Items       []TaskTemplateItem `gorm:"foreignKey:TemplateID" json:"items"`
+	CreatedAt   time.Time          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TaskTemplateItem 模板中的一个任务，开始和截止时间以相对实例化锚点的分钟数表示
+type TaskTemplateItem struct {
+	ID                 uint     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID         uint     `gorm:"index" json:"template_id"`
+	Position           int      `gorm:"not null;default:0" json:"position"`
+	Title              string   `gorm:"size:255;not null" json:"title"`
+	Category           string   `gorm:"size:100" json:"category"`
+	Location           string   `gorm:"size:255" json:"location"`
+	Description        string   `gorm:"type:text" json:"description"`
+	Priority           string   `gorm:"size:2" json:"priority"`
+	Tags               []string `gorm:"type:text;serializer:json" json:"tags"`
+	Checklist          []string `gorm:"type:text;serializer:json" json:"checklist"`
+	StartOffsetMinutes int      `gorm:"not null;default:0" json:"start_offset_minutes"` // 开始时间相对锚点的分钟数，可以为负
+	DueOffsetMinutes   int      `gorm:"not null;default:0" json:"due_offset_minutes"`   // 截止时间相对锚点的分钟数，不早于开始时间
+}
+
+// withTemplateItems 按顺序预加载模板中的任务
+func withTemplateItems(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Items", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position").Order("id")
+	})
+}
+
+// ListTemplates 按名称获取用户的全部模板
+func ListTemplates(userID uint) ([]TaskTemplate, error) {
+	var templates []TaskTemplate
+	err := db.DB.Scopes(withTemplateItems).Where("user_id = ?", userID).Order("name").Find(&templates).Error
+	return templates, err
+}
+
+// GetTemplateByIdTx 在指定事务中获取用户的模板
+func GetTemplateByIdTx(tx *gorm.DB, userID, id uint) (*TaskTemplate, error) {
+	var template TaskTemplate
+	err := tx.Scopes(withTemplateItems).Where("user_id = ?", userID).First(&template, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	return &template, err
+}
+
+// GetTemplateByNameTx 在指定事务中按名称获取用户的模板
+func GetTemplateByNameTx(tx *gorm.DB, userID uint, name string) (*TaskTemplate, error) {
+	var template TaskTemplate
+	err := tx.Scopes(withTemplateItems).Where("user_id = ? AND name = ?", userID, name).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	return &template, err
+}
+
+// CreateTemplateTx 在指定事务中创建模板及其中的任务
+func CreateTemplateTx(tx *gorm.DB, template *TaskTemplate) error {
+	items := template.Items
+	if err := tx.Omit("Items").Create(template).Error; err != nil {
+		return err
+	}
+	return createTemplateItemsTx(tx, template, items)
+}
+
+// UpdateTemplateTx 在指定事务中保存模板，模板中的任务整体替换为 template.Items
+func UpdateTemplateTx(tx *gorm.DB, template *TaskTemplate) error {
+	if err := tx.Model(template).Where("user_id = ?", template.UserID).
+		Select("name", "description", "project_name", "updated_at").Updates(template).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("template_id = ?", template.ID).Delete(&TaskTemplateItem{}).Error; err != nil {
+		return err
+	}
+	return createTemplateItemsTx(tx, template, template.Items)
+}
+
+func createTemplateItemsTx(tx *gorm.DB, template *TaskTemplate, items []TaskTemplateItem) error {
+	template.Items = make([]TaskTemplateItem, len(items))
+	for i, item := range items {
+		item.ID, item.TemplateID, item.Position = 0, template.ID, i
+		template.Items[i] = item
+	}
+	if len(template.Items) == 0 {
+		return nil
+	}
+	return tx.Create(&template.Items).Error
+}
+
+// DeleteTemplate 删除模板，已经实例化出的任务不受影响
+func DeleteTemplate(userID, id uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&TaskTemplate{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTemplateNotFound
+		}
+		return tx.Where("template_id = ?", id).Delete(&TaskTemplateItem{}).Error
+	})
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListTrashedTasks 获取回收站中的任务，最近删除的在前
+func ListTrashedTasks(userID uint) ([]Task, error) {
+	var tasks []Task
+	err := db.DB.Unscoped().Scopes(OwnedBy(userID)).
+		Where("tasks.deleted_at IS NOT NULL").
+		Order("tasks.deleted_at DESC, tasks.id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// GetTrashedTask 获取回收站中的单个任务
+func GetTrashedTask(userID, id uint) (*Task, error) {
+	return GetTrashedTaskTx(db.DB, userID, id)
+}
+
+// GetTrashedTaskTx 在指定事务中获取回收站中的单个任务
+func GetTrashedTaskTx(tx *gorm.DB, userID, id uint) (*Task, error) {
+	var task Task
+	err := tx.Unscoped().Scopes(OwnedBy(userID)).Scopes(withTaskAssociations).
+		Where("tasks.deleted_at IS NOT NULL").
+		First(&task, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &task, ErrTaskNotFound
+	}
+	return &task, err
+}
+
+// RestoreTaskTx 在指定事务中将任务及与其同一次删除的后代、例外实例移出回收站
+// 父任务或所属重复任务已不存在时，恢复后的任务提升为独立的顶层任务
+func RestoreTaskTx(tx *gorm.DB, task *Task) error {
+	ids, err := trashedBatch(tx, task)
+	if err != nil {
+		return err
+	}
+	err = tx.Unscoped().Model(&Task{}).Scopes(OwnedBy(task.UserID)).
+		Where("tasks.id IN ?", ids).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	detach := map[string]interface{}{}
+	if task.ParentID != nil && !taskExists(tx, task.UserID, *task.ParentID) {
+		detach["parent_id"] = nil
+	}
+	if task.SeriesID != nil && !taskExists(tx, task.UserID, *task.SeriesID) {
+		detach["series_id"], detach["recurrence_id"] = nil, nil
+	}
+	if len(detach) > 0 {
+		return tx.Model(&Task{}).Scopes(OwnedBy(task.UserID)).Where("tasks.id = ?", task.ID).
+			Updates(detach).Error
+	}
+	return nil
+}
+
+// PurgeTask 彻底删除回收站中的任务及与其同一次删除的后代、例外实例，
+// 返回被删除附件在存储后端中的位置，由调用方在事务提交后删除文件
+func PurgeTask(task *Task) ([]string, error) {
+	var keys []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedBatch(tx, task)
+		if err != nil {
+			return err
+		}
+		keys, err = purgeTasks(tx, task.UserID, ids)
+		return err
+	})
+	return keys, err
+}
+
+// PurgeTrashedBefore 彻底删除在 cutoff 之前移入回收站的任务，每次最多处理 limit 个，
+// 返回删除的数量以及被删除附件在存储后端中的位置
+func PurgeTrashedBefore(cutoff time.Time, limit int) (int, []string, error) {
+	var tasks []Task
+	err := db.DB.Unscoped().Select("id", "user_id").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("id").
+		Limit(limit).
+		Find(&tasks).Error
+	if err != nil || len(tasks) == 0 {
+		return 0, nil, err
+	}
+
+	byUser := make(map[uint][]uint)
+	for _, t := range tasks {
+		byUser[t.UserID] = append(byUser[t.UserID], t.ID)
+	}
+	var keys []string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for userID, ids := range byUser {
+			userKeys, err := purgeTasks(tx, userID, ids)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, userKeys...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(tasks), keys, nil
+}
+
+// trashedBatch 获取与 root 在同一次删除中移入回收站的任务ID：root、其后代以及这些任务的例外实例
+func trashedBatch(tx *gorm.DB, root *Task) ([]uint, error) {
+	at := root.DeletedAt.Time
+	ids := []uint{root.ID}
+	visited := map[uint]bool{root.ID: true}
+	frontier := []uint{root.ID}
+	for len(frontier) > 0 {
+		var children []uint
+		err := tx.Unscoped().Model(&Task{}).Scopes(OwnedBy(root.UserID)).
+			Where("tasks.parent_id IN ? AND tasks.deleted_at = ?", frontier, at).
+			Pluck("tasks.id", &children).Error
+		if err != nil {
+			return nil, err
+		}
+		frontier = frontier[:0]
+		for _, id := range children {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			ids = append(ids, id)
+			frontier = append(frontier, id)
+		}
+	}
+
+	var exceptions []uint
+	err := tx.Unscoped().Model(&Task{}).Scopes(OwnedBy(root.UserID)).
+		Where("tasks.series_id IN ? AND tasks.deleted_at = ?", ids, at).
+		Pluck("tasks.id", &exceptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(ids, exceptions...), nil
+}
+
+// purgeTasks 彻底删除任务及其依赖关系、状态记录、附件、计时记录、专注记录等，返回附件在存储后端中的位置
+func purgeTasks(tx *gorm.DB, userID uint, ids []uint) ([]string, error) {
+	keys, err := attachmentKeysTx(tx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Scopes(OwnedBy(userID)).Delete(&Task{}, ids).Error; err != nil {
+		return nil, err
+	}
+	if err := deleteDependenciesOf(tx, userID, ids); err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&TaskStatusHistory{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&TaskTag{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&ChecklistItem{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&TaskComment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&Attachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&TaskOverdueRecord{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&TimeEntry{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&FocusSession{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", userID, ids).Delete(&FocusSegment{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// taskExists 判断任务存在且不在回收站中
+func taskExists(tx *gorm.DB, userID, id uint) bool {
+	var count int64
+	tx.Model(&Task{}).Scopes(OwnedBy(userID)).Where("tasks.id = ?", id).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"AITodo/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListChildren 获取指定父任务的直接子任务
+func ListChildren(userID uint, parentIDs []uint) ([]Task, error) {
+	return ListChildrenTx(db.DB, userID, parentIDs)
+}
+
+// ListChildrenTx 在指定事务中获取指定父任务的直接子任务
+func ListChildrenTx(tx *gorm.DB, userID uint, parentIDs []uint) ([]Task, error) {
+	var tasks []Task
+	err := tx.Scopes(OwnedBy(userID)).
+		Where("tasks.parent_id IN ?", parentIDs).
+		Order("tasks.start_date, tasks.id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// GetSubtree 按层级获取任务的全部后代，不包含任务本身
+func GetSubtree(userID, rootID uint) ([]Task, error) {
+	return GetSubtreeTx(db.DB, userID, rootID)
+}
+
+// GetSubtreeTx 在指定事务中按层级获取任务的全部后代
+func GetSubtreeTx(tx *gorm.DB, userID, rootID uint) ([]Task, error) {
+	var descendants []Task
+	visited := map[uint]bool{rootID: true}
+	frontier := []uint{rootID}
+	for len(frontier) > 0 {
+		children, err := ListChildrenTx(tx, userID, frontier)
+		if err != nil {
+			return nil, err
+		}
+		frontier = frontier[:0]
+		for _, child := range children {
+			// 理论上不会成环，这里防御数据异常导致的死循环
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			descendants = append(descendants, child)
+			frontier = append(frontier, child.ID)
+		}
+	}
+	return descendants, nil
+}
+
+// DeleteTaskPromoteChildrenTx 在指定事务中将任务移入回收站，并将其直接子任务提升到该任务的父任务下
+func DeleteTaskPromoteChildrenTx(tx *gorm.DB, task *Task) error {
+	err := tx.Model(&Task{}).Scopes(OwnedBy(task.UserID)).
+		Where("tasks.parent_id = ?", task.ID).
+		Updates(map[string]interface{}{
+			"parent_id": task.ParentID,
+			"version":   gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	result := tx.Model(&Task{}).Scopes(OwnedBy(task.UserID)).Where("tasks.id = ?", task.ID).
+		Updates(trashColumns(now))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+	return tx.Model(&Task{}).Scopes(OwnedBy(task.UserID)).Where("tasks.series_id = ?", task.ID).
+		Updates(trashColumns(now)).Error
+}
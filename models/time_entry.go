@@ -0,0 +1,115 @@
+package models
+
+import (
+	"AITodo/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxEstimateMinutes 任务预计用时的上限（分钟）
+const MaxEstimateMinutes = 100000
+
+// ErrTimeEntryNotFound 计时记录不存在或不属于当前用户
+var ErrTimeEntryNotFound = errors.New("计时记录不存在")
+
+// TimeEntry 任务的一段计时记录，EndedAt 为空表示计时器仍在运行
+// Running 在运行中时为 true、停止后为 NULL，配合 (user_id, running) 唯一索引保证每个用户最多一个运行中的计时器
+type TimeEntry struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    uint       `gorm:"index" json:"task_id"`
+	UserID    uint       `gorm:"index;uniqueIndex:idx_time_entry_running,priority:1" json:"-"`
+	StartedAt time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Seconds   int64      `gorm:"not null;default:0" json:"seconds"` // 计时时长，运行中的计时器为截至查询时的时长
+	Note      string     `gorm:"size:255" json:"note"`
+	Manual    bool       `gorm:"not null;default:false" json:"manual"` // 是否为手动补录
+	Running   *bool      `gorm:"uniqueIndex:idx_time_entry_running,priority:2" json:"-"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AfterFind 为运行中的计时器计算截至当前的时长
+func (e *TimeEntry) AfterFind(tx *gorm.DB) error {
+	if e.EndedAt == nil {
+		e.Seconds = int64(time.Since(e.StartedAt) / time.Second)
+	}
+	return nil
+}
+
+// IsRunning 计时器是否仍在运行
+func (e *TimeEntry) IsRunning() bool {
+	return e.EndedAt == nil
+}
+
+// ListTimeEntries 获取任务的计时记录，按开始时间倒序排列
+func ListTimeEntries(userID, taskID uint) ([]TimeEntry, error) {
+	var entries []TimeEntry
+	err := db.DB.Where("user_id = ? AND task_id = ?", userID, taskID).
+		Order("started_at DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// GetRunningEntryTx 在指定事务中获取用户运行中的计时器，没有时返回 nil
+// lock 为 true 时锁定用户记录，使同一用户的开始、停止计时依次进行
+func GetRunningEntryTx(tx *gorm.DB, userID uint, lock bool) (*TimeEntry, error) {
+	if lock {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return nil, err
+		}
+	}
+	var entry TimeEntry
+	err := tx.Where("user_id = ? AND running = ?", userID, true).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetTimeEntryTx 在指定事务中获取任务的计时记录
+func GetTimeEntryTx(tx *gorm.DB, userID, taskID, id uint) (*TimeEntry, error) {
+	var entry TimeEntry
+	err := tx.Where("user_id = ? AND task_id = ?", userID, taskID).First(&entry, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTimeEntryNotFound
+	}
+	return &entry, err
+}
+
+// CreateTimeEntryTx 在指定事务中创建计时记录，EndedAt 为空时作为运行中的计时器创建
+func CreateTimeEntryTx(tx *gorm.DB, entry *TimeEntry) error {
+	entry.Running = nil
+	if entry.EndedAt == nil {
+		running := true
+		entry.Running = &running
+	}
+	return tx.Create(entry).Error
+}
+
+// StopTx 在指定事务中停止计时器，记录结束时间和时长
+func (e *TimeEntry) StopTx(tx *gorm.DB, at time.Time) error {
+	if at.Before(e.StartedAt) {
+		at = e.StartedAt
+	}
+	e.EndedAt = &at
+	e.Seconds = int64(at.Sub(e.StartedAt) / time.Second)
+	e.Running = nil
+	return tx.Model(e).Select("ended_at", "seconds", "running").Updates(e).Error
+}
+
+// DeleteTimeEntryTx 在指定事务中删除计时记录
+func DeleteTimeEntryTx(tx *gorm.DB, entry *TimeEntry) error {
+	result := tx.Where("user_id = ?", entry.UserID).Delete(&TimeEntry{}, entry.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTimeEntryNotFound
+	}
+	return nil
+}
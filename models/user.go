@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -14,21 +15,35 @@ const (
 	UserStatusBanned  = 2
 )
 
+// 任务逾期后的处理策略，默认保持不变，标记失败和顺延需要用户主动开启
+const (
+	OverduePolicyFail     = "fail"     // 标记为失败
+	OverduePolicyRollover = "rollover" // 顺延到今天
+	OverduePolicyLeave    = "leave"    // 保持不变
+)
+
 type User struct {
-	ID       uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserName string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"user_name"`
-	Password string    `gorm:"type:varchar(255);not null" json:"-"` //密码哈希不序列化到json
-	Phone    *string   `gorm:"type:varchar(20);uniqueIndex;default:NUll" json:"phone"`
-	Email    *string   `gorm:"type:varchar(255);uniqueIndex;default:NULL" json:"email"` //带*表示允许为NULL
-	Status   int       `gorm:"type:tinyint(1);default:0" json:"status"`
-	CreateAt time.Time `gorm:"column:create_at;type:datetime;autoCreateTime" json:"create_at"`
-	UpdateAt time.Time `gorm:"column:create_at;type:datetime;autoUpdateTime" json:"update_at"`
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserName      string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"user_name"`
+	Password      string    `gorm:"type:varchar(255);not null" json:"-"` //密码哈希不序列化到json
+	Phone         *string   `gorm:"type:varchar(20);uniqueIndex;default:NUll" json:"phone"`
+	Email         *string   `gorm:"type:varchar(255);uniqueIndex;default:NULL" json:"email"` //带*表示允许为NULL
+	Status        int       `gorm:"type:tinyint(1);default:0" json:"status"`
+	OverduePolicy string    `gorm:"type:varchar(20);default:'leave'" json:"overdue_policy"`
+	CreateAt      time.Time `gorm:"column:create_at;type:datetime;autoCreateTime" json:"create_at"`
+	UpdateAt      time.Time `gorm:"column:create_at;type:datetime;autoUpdateTime" json:"update_at"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+// LockUserTx 在指定事务中锁定用户记录，使同一用户需要整体校验的修改（如依赖关系、任务层级）依次进行
+func LockUserTx(tx *gorm.DB, userID uint) error {
+	var user User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+}
+
 func SearchByPhone(phone string) (*User, error) {
 	var user User
 	result := db.DB.Where("phone=?", phone).First(&user)
@@ -67,3 +82,20 @@ func PhoneExists(phone string) (bool, error) {
 func CreateUser(user *User) error {
 	return db.DB.Create(user).Error
 }
+
+func GetUserByID(id uint) (*User, error) {
+	var user User
+	result := db.DB.First(&user, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// UpdateOverduePolicy 修改用户的逾期处理策略，策略为空时恢复为保持不变
+func UpdateOverduePolicy(userID uint, policy string) error {
+	if policy == "" {
+		policy = OverduePolicyLeave
+	}
+	return db.DB.Model(&User{}).Where("id = ?", userID).UpdateColumn("overdue_policy", policy).Error
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"AITodo/config"
 	"AITodo/controllers"
 	"AITodo/middleware"
 	"AITodo/util"
@@ -13,10 +14,14 @@ func SetupRoutes(router *gin.Engine) {
 	// 全局应用 CORS 中间件
 	router.Use(middleware.SetupCORS())
 
+	// 创建类请求支持 Idempotency-Key，避免网络重试产生重复任务
+	idempotency := middleware.Idempotency(config.Cfg.Idempotency.TTL, config.Cfg.Idempotency.PendingTTL)
+
 	// AI 相关路由（假设需要认证）
 	ai := router.Group("/ai").Use(middleware.JWTAuth())
 	{
-		ai.POST("/assist", controllers.AIAssistant)
+		ai.POST("/assist", idempotency, controllers.AIAssistant)
+		ai.POST("/undo", controllers.UndoLastAIAction)
 	}
 
 	// 用户管理路由（无需认证）
@@ -25,15 +30,151 @@ func SetupRoutes(router *gin.Engine) {
 		user.POST("/login", controllers.LoginHandler)
 		user.POST("/register", controllers.RegisterHandler)
 		user.GET("/send_code", controllers.SendVerifyCodeHandler)
+		user.GET("/settings/overdue_policy", middleware.JWTAuth(), controllers.GetOverduePolicy)
+		user.PUT("/settings/overdue_policy", middleware.JWTAuth(), controllers.UpdateOverduePolicy)
 	}
 
 	// 任务管理路由（需要认证）
 	task := router.Group("/task").Use(middleware.JWTAuth())
 	{
 		task.GET("/", controllers.GetAllTasks)
-		task.POST("/", controllers.CreateTask)
+		task.GET("/matrix", controllers.GetEisenhowerMatrix)
+		task.GET("/board", controllers.GetBoard)
+		task.GET("/:id", controllers.GetTask)
+		task.POST("/", idempotency, controllers.CreateTask)
 		task.PUT("/:id", controllers.UpdateTask)
+		task.PATCH("/:id", controllers.PatchTask)
 		task.DELETE("/:id", controllers.DeleteTask)
+		task.POST("/bulk", controllers.BulkTasks)
+
+		// 修改记录
+		task.GET("/:id/history", controllers.GetTaskHistory)
+		task.POST("/:id/undo", controllers.UndoTask)
+
+		// 回收站
+		task.GET("/trash", controllers.GetTrash)
+		task.POST("/:id/restore", controllers.RestoreTask)
+		task.DELETE("/trash/:id", controllers.PurgeTask)
+
+		// 子任务
+		task.GET("/:id/tree", controllers.GetTaskTree)
+		task.GET("/:id/status_history", controllers.GetStatusHistory)
+		task.POST("/:id/move", controllers.MoveTask)
+		task.POST("/:id/board_move", controllers.MoveOnBoard)
+
+		// 任务依赖
+		task.GET("/actionable", controllers.GetActionableTasks)
+		task.GET("/critical_path", controllers.GetCriticalPath)
+		task.GET("/:id/dependencies", controllers.GetDependencies)
+		task.POST("/:id/dependencies", controllers.AddDependency)
+		task.DELETE("/:id/dependencies/:blocker_id", controllers.RemoveDependency)
+
+		// 重复任务
+		task.GET("/occurrences", controllers.GetOccurrences)
+		task.GET("/:id/occurrences", controllers.GetTaskOccurrences)
+		task.PATCH("/:id/occurrences", controllers.UpdateOccurrence)
+		task.POST("/:id/occurrences/complete", controllers.CompleteOccurrence)
+		task.DELETE("/:id/occurrences", controllers.DeleteOccurrence)
+
+		// 检查项
+		task.GET("/:id/checklist", controllers.GetChecklist)
+		task.POST("/:id/checklist", controllers.AddChecklistItem)
+		task.PUT("/:id/checklist/order", controllers.ReorderChecklist)
+		task.PATCH("/:id/checklist/:item_id", controllers.UpdateChecklistItem)
+		task.DELETE("/:id/checklist/:item_id", controllers.DeleteChecklistItem)
+
+		// 评论与动态
+		task.GET("/:id/comments", controllers.GetComments)
+		task.POST("/:id/comments", controllers.CreateComment)
+		task.PATCH("/:id/comments/:comment_id", controllers.UpdateComment)
+		task.DELETE("/:id/comments/:comment_id", controllers.DeleteComment)
+		task.GET("/:id/activity", controllers.GetTaskActivity)
+
+		// 附件
+		task.GET("/:id/attachments", controllers.GetAttachments)
+		task.POST("/:id/attachments", controllers.UploadAttachment)
+		task.DELETE("/:id/attachments/:attachment_id", controllers.DeleteAttachment)
+
+		// 计时
+		task.POST("/:id/timer/start", controllers.StartTimer)
+		task.GET("/:id/time_entries", controllers.GetTimeEntries)
+		task.POST("/:id/time_entries", controllers.AddTimeEntry)
+		task.DELETE("/:id/time_entries/:entry_id", controllers.DeleteTimeEntry)
+
+		// 番茄钟
+		task.GET("/:id/focus", controllers.GetFocusSessions)
+		task.POST("/:id/focus", controllers.StartFocusSession)
+	}
+
+	// 计时器路由（需要认证），每个用户同一时间只有一个运行中的计时器
+	timer := router.Group("/timer").Use(middleware.JWTAuth())
+	{
+		timer.GET("/", controllers.GetRunningTimer)
+		timer.POST("/stop", controllers.StopTimer)
+	}
+
+	// 番茄钟路由（需要认证），会话状态保存在服务端，任意设备都可以继续操作当前会话
+	focus := router.Group("/focus").Use(middleware.JWTAuth())
+	{
+		focus.GET("/", controllers.GetCurrentFocusSession)
+		focus.POST("/pause", controllers.PauseFocusSession)
+		focus.POST("/resume", controllers.ResumeFocusSession)
+		focus.POST("/interrupt", controllers.InterruptFocusSession)
+		focus.POST("/next", controllers.NextFocusPomodoro)
+		focus.POST("/stop", controllers.StopFocusSession)
+	}
+
+	// 附件路由，下载地址自带签名，无需认证
+	attachment := router.Group("/attachment")
+	{
+		attachment.GET("/usage", middleware.JWTAuth(), controllers.GetAttachmentUsage)
+		attachment.GET("/:id/download", controllers.DownloadAttachment)
+	}
+
+	// 日历路由（需要认证）
+	calendar := router.Group("/calendar").Use(middleware.JWTAuth())
+	{
+		calendar.GET("/", controllers.GetCalendar)
+	}
+
+	// 项目管理路由（需要认证）
+	project := router.Group("/project").Use(middleware.JWTAuth())
+	{
+		project.GET("/", controllers.GetProjects)
+		project.POST("/", controllers.CreateProject)
+		project.PUT("/order", controllers.ReorderProjects)
+		project.PATCH("/:id", controllers.UpdateProject)
+		project.DELETE("/:id", controllers.DeleteProject)
+	}
+
+	// 类别管理路由（需要认证）
+	category := router.Group("/category").Use(middleware.JWTAuth())
+	{
+		category.GET("/", controllers.GetCategories)
+		category.POST("/", controllers.CreateCategory)
+		category.PATCH("/:id", controllers.UpdateCategory)
+		category.DELETE("/:id", controllers.DeleteCategory)
+	}
+
+	// 标签管理路由（需要认证）
+	tag := router.Group("/tag").Use(middleware.JWTAuth())
+	{
+		tag.GET("/", controllers.GetTags)
+		tag.POST("/", controllers.CreateTag)
+		tag.PATCH("/:id", controllers.RenameTag)
+		tag.POST("/:id/merge", controllers.MergeTag)
+		tag.DELETE("/:id", controllers.DeleteTag)
+	}
+
+	// 任务模板路由（需要认证）
+	template := router.Group("/template").Use(middleware.JWTAuth())
+	{
+		template.GET("/", controllers.GetTemplates)
+		template.POST("/", controllers.CreateTemplate)
+		template.GET("/:id", controllers.GetTemplate)
+		template.PUT("/:id", controllers.UpdateTemplate)
+		template.DELETE("/:id", controllers.DeleteTemplate)
+		template.POST("/:id/instantiate", idempotency, controllers.InstantiateTemplate)
 	}
 
 	analyticsGroup := router.Group("/analytics").Use(middleware.JWTAuth())
@@ -42,6 +183,9 @@ func SetupRoutes(router *gin.Engine) {
 		analyticsGroup.GET("/trend", controllers.GetTrendHandler)
 		analyticsGroup.GET("/category_distribution", controllers.GetCategoryDistributionHandler)
 		analyticsGroup.GET("/heatmap", controllers.GetHeatmapHandler)
+		analyticsGroup.GET("/tag_distribution", controllers.GetTagDistributionHandler)
+		analyticsGroup.GET("/time_tracking", controllers.GetTimeTrackingHandler)
+		analyticsGroup.GET("/focus", controllers.GetFocusHandler)
 		analyticsGroup.POST("/ai_report", controllers.AIAnalytics)
 	}
 	// 认证相关路由
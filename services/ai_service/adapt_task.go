@@ -1,6 +1,7 @@
 package ai_service
 
 import (
+	"AITodo/dto"
 	"AITodo/models"
 	"AITodo/services"
 	"fmt"
@@ -17,17 +18,17 @@ var (
 	ErrMissStartDate  = fmt.Errorf("start_date为必填字段")
 )
 
-// CreateTask 适配器
-func adaptCreateTask(id uint, args map[string]interface{}) (interface{}, error) {
+// CreateTask 适配器，将工具调用参数转换为创建操作
+func adaptCreateTask(id uint, args map[string]interface{}) (dto.BulkOperation, error) {
 	// 参数验证
 	if err := validateCreateArgs(args); err != nil {
-		return nil, err
+		return dto.BulkOperation{}, err
 	}
 
 	// 提取 task 字段
 	task, ok := args["task"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("task字段缺失或格式错误")
+		return dto.BulkOperation{}, fmt.Errorf("task字段缺失或格式错误")
 	}
 	startDate, err := parseTime(task["start_date"])
 	if err != nil {
@@ -42,81 +43,129 @@ func adaptCreateTask(id uint, args map[string]interface{}) (interface{}, error)
 	taskModel := models.Task{
 		UserID:      id,
 		Title:       task["title"].(string),
-		Category:    task["category"].(string),
+		Category:    parseString(task["category"]),
 		Location:    parseString(task["location"]),
 		Description: parseString(task["description"]),
 		Status:      parseStatus(task["status"]),
+		Priority:    parsePriority(task["priority"]),
 		StartDate:   startDate,
 		DueDate:     dueDate,
+		Recurrence:  parseString(task["recurrence"]),
+	}
+	if minutes, ok := task["estimate_minutes"].(float64); ok && minutes > 0 {
+		taskModel.EstimateMinutes = int(minutes)
+	}
+	for _, name := range parseStringList(task["tags"]) {
+		taskModel.Tags = append(taskModel.Tags, models.Tag{Name: name})
+	}
+	for _, content := range parseStringList(task["checklist"]) {
+		taskModel.Checklist = append(taskModel.Checklist, models.ChecklistItem{Content: content})
 	}
 
-	err = services.CreateTask(taskModel)
+	return dto.BulkOperation{Op: services.BulkCreate, Task: &taskModel, ProjectName: parseString(task["project"])}, nil
+}
+
+// InstantiateTemplate 适配器，将工具调用参数转换为模板中全部任务的创建操作
+func adaptInstantiateTemplate(userID uint, args map[string]interface{}) ([]dto.BulkOperation, error) {
+	name := parseString(args["name"])
+	if name == "" {
+		return nil, fmt.Errorf("name为必填字段")
+	}
+	anchor, err := services.ParseTemplateAnchor(parseString(args["anchor"]), time.Now())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("anchor 解析失败: %v", err)
 	}
-	return nil, nil
+	return services.TemplateOperationsByName(userID, name, anchor)
 }
 
-// UpdateTask 适配器
-func adaptUpdateTask(args map[string]interface{}) (interface{}, error) {
+// UpdateTask 适配器，将工具调用参数转换为修改操作
+// 只更新模型明确给出的字段，避免未提及的字段被清空
+func adaptUpdateTask(userID uint, args map[string]interface{}) (dto.BulkOperation, error) {
 	// 参数验证
 	if err := validateUpdateArgs(args); err != nil {
-		return nil, err
+		return dto.BulkOperation{}, err
 	}
 
-	idStr := args["id"].(string)
-	id, err := strconv.ParseUint(idStr, 10, 64)
-	// 提取 task 字段
-	req, ok := args["task"].(map[string]interface{})
+	id, err := parseUint(args["id"])
 	if err != nil {
-		return nil, fmt.Errorf("invalid id format: %v", err)
+		return dto.BulkOperation{}, fmt.Errorf("invalid id format: %v", err)
 	}
+	// 提取 task 字段
+	req, ok := args["task"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("task字段缺失或格式错误")
+		return dto.BulkOperation{}, fmt.Errorf("task字段缺失或格式错误")
 	}
 
-	startDate, err := parseTime(req["start_date"])
+	patch, err := buildTaskPatch(req)
 	if err != nil {
-		log.Printf("任务 %s 的 start_date 解析失败: %v", req["title"], err)
-	}
-	dueDate, err := parseTime(req["due_date"])
-	if err != nil {
-		log.Printf("任务 %s 的 due_date 解析失败: %v", req["title"], err)
+		return dto.BulkOperation{}, err
 	}
+	// 以检索时的版本号写入，任务在检索后被修改时返回版本冲突
+	version, _ := args["version"].(uint)
+	return dto.BulkOperation{Op: services.BulkUpdate, ID: id, Version: version, Patch: &patch}, nil
+}
 
-	// 构建 Task 对象
-	taskModel := models.Task{
-		UserID:      uint(id),
-		Title:       req["title"].(string),
-		Category:    req["category"].(string),
-		Location:    parseString(req["location"]),
-		Description: parseString(req["description"]),
-		Status:      parseStatus(req["status"]),
-		StartDate:   startDate,
-		DueDate:     dueDate,
+// buildTaskPatch 根据模型返回的字段构建部分更新请求，缺失或为空的字段不修改
+// title 只用于定位任务，重命名使用 new_title
+func buildTaskPatch(req map[string]interface{}) (dto.TaskPatch, error) {
+	var patch dto.TaskPatch
+	if s, ok := req["new_title"].(string); ok && s != "" {
+		patch.Title = &s
 	}
-
-	updatedTask, err := services.UpdateTask(uint(id), taskModel)
-	if err != nil {
-		return nil, fmt.Errorf("更新任务失败: %v", err)
+	if s, ok := req["category"].(string); ok && s != "" {
+		patch.Category = &s
 	}
-	return updatedTask, nil
-
+	if s, ok := req["location"].(string); ok && s != "" {
+		patch.Location = &s
+	}
+	if s, ok := req["description"].(string); ok && s != "" {
+		patch.Description = &s
+	}
+	if s, ok := req["recurrence"].(string); ok && s != "" {
+		patch.Recurrence = &s
+	}
+	if s, ok := req["status"].(string); ok && s != "" {
+		status := parseStatus(s)
+		patch.Status = &status
+	}
+	if s, ok := req["priority"].(string); ok && models.IsValidPriority(s) {
+		patch.Priority = &s
+	}
+	if minutes, ok := req["estimate_minutes"].(float64); ok && minutes >= 0 {
+		estimate := int(minutes)
+		patch.EstimateMinutes = &estimate
+	}
+	if _, ok := req["tags"]; ok {
+		tags := parseStringList(req["tags"])
+		patch.Tags = &tags
+	}
+	for key, target := range map[string]**time.Time{
+		"start_date": &patch.StartDate,
+		"due_date":   &patch.DueDate,
+	} {
+		if s, ok := req[key].(string); !ok || s == "" {
+			continue
+		}
+		t, err := parseTime(req[key])
+		if err != nil {
+			return patch, fmt.Errorf("%s 解析失败: %v", key, err)
+		}
+		*target = &t
+	}
+	return patch, nil
 }
 
-// DeleteTask 适配器
-func adaptDeleteTask(args map[string]interface{}) (interface{}, error) {
+// DeleteTask 适配器，将工具调用参数转换为删除操作
+func adaptDeleteTask(userID uint, args map[string]interface{}) (dto.BulkOperation, error) {
 	id, err := parseUint(args["id"])
 	if err != nil {
-		return nil, ErrInvalidID
+		return dto.BulkOperation{}, ErrInvalidID
 	}
 	// 添加存在性检查
-	if _, err := models.GetTaskById(id); err != nil {
-		return nil, fmt.Errorf("任务不存在")
+	if _, err := models.GetTaskById(userID, id); err != nil {
+		return dto.BulkOperation{}, fmt.Errorf("任务不存在")
 	}
-
-	err = services.DeleteTask(id)
-	return nil, err
+	return dto.BulkOperation{Op: services.BulkDelete, ID: id}, nil
 }
 
 // 参数验证函数
@@ -140,16 +189,15 @@ func validateCreateArgs(args map[string]interface{}) error {
 }
 
 func validateUpdateArgs(args map[string]interface{}) error {
-	task, ok := args["task"].(map[string]interface{})
-	if !ok {
+	if _, ok := args["task"].(map[string]interface{}); !ok {
 		return fmt.Errorf("task字段缺失或格式错误")
 	}
 
-	// 验证 id 是否存在
-	if _, ok := task["id"].(float64); !ok {
+	// 验证 id 是否存在（由 searchTask 检索后写入）
+	if _, ok := args["id"]; !ok {
 		return ErrInvalidID
 	}
-	return validateCreateArgs(args)
+	return nil
 }
 
 // 类型转换辅助函数
@@ -174,15 +222,30 @@ func parseString(value interface{}) string {
 	return ""
 }
 
-func parseStatus(value interface{}) string {
-	status := "pending"
-	if s, ok := value.(string); ok {
-		switch s {
-		case "pending", "in_progress", "completed":
-			status = s
+// parseStringList 解析字符串数组，忽略非字符串元素
+func parseStringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
 		}
 	}
-	return status
+	return list
+}
+
+func parseStatus(value interface{}) string {
+	if s, ok := value.(string); ok && models.IsValidTaskStatus(s) {
+		return s
+	}
+	return models.TaskStatusPending
+}
+
+func parsePriority(value interface{}) string {
+	if s, ok := value.(string); ok && models.IsValidPriority(s) {
+		return s
+	}
+	return models.DefaultPriority
 }
 
 func parseTime(value interface{}) (time.Time, error) {
@@ -1,8 +1,13 @@
 package ai_service
 
 import (
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/services"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
 	"time"
 )
@@ -12,7 +17,15 @@ type AIResult struct {
 	Err      error
 }
 
-type ToolFunction func(map[string]interface{}) (interface{}, error)
+// AIBatch 一次 AI 会话中工具调用的执行结果
+type AIBatch struct {
+	ID        string // 本次会话的 AI 操作者 ID，同一批修改共用
+	Committed bool   // 工具调用产生的修改是否已提交
+}
+
+// ToolFunction 将工具调用参数转换为批量操作，同一次回复中的全部工具调用在一个事务中执行
+// 一次调用可以对应多个操作，例如实例化模板会创建模板中的全部任务
+type ToolFunction func(map[string]interface{}) ([]dto.BulkOperation, error)
 
 /*// 异步处理入口
 func ProcessTaskWithAIAsync(input string, callback func(string, error)) {
@@ -23,7 +36,8 @@ func ProcessTaskWithAIAsync(input string, callback func(string, error)) {
 	})
 }*/
 
-func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, error) {
+// ProcessTaskWithAI 调用大模型解析用户输入并执行工具调用，返回后续生成回复所需的上下文消息和本次修改的提交情况
+func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, AIBatch, error) {
 	//两种处理方式：1.是先使用大模型对字符串进行分析analyze，判断用户是什么操作，是创建、更新还是删除。如果是创建，则再调用模型返回创建所需要的参数；若是更新或删除，则首先调用搜索任务函数searchTask，让大模型返回搜索所需要的参数关键字string，然后搜所函数返回id和新的任务参数（若为删除则为空字段），然后再根据是更新还是删除调用对应函数。总共调用两次模型（searchTask先查）
 	//2.先让大模型分析用户输入的字符串，然后选择调用的函数，返回对应函数所需要的参数（函数名称和task）；根据函数名称调用对应函数，若为创建，则直接调用创建函数，若为更新或删除则根据返回的task参数调用searchTask函数（也可使用大模型实现检索，或者普通的方式），若返回为空则直接进行返回，查到了对应的id则进行删除或更新操作，总共调用一次模型（searchTask后查）
 
@@ -34,13 +48,14 @@ func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, err
 				"创建任务：当用户请求添加或创建任务时，调用 CreateTask 函数。" +
 				"删除任务：当用户请求删除或取消任务时，或者使用不想不要这种带否定的字段时，调用 DeleteTask 函数。" +
 				"更新任务：当用户请求修改或更新任务时，调用 UpdateTask 函数。" +
+				"实例化模板：当用户要求按已有的模板创建一组任务时（例如“按入职模板从下周一开始建任务”），调用 InstantiateTemplate 函数，不要逐个调用 CreateTask。" +
 				"在调用 CreateTask 或 DeleteTask 时，确保返回的参数符合用户描述，并结合历史对话中的任务对象。如果任务与历史任务相关且某些字段值已存在，请尽量保持这些字段值一致。" +
 				"时间处理逻辑：" +
 				"时间格式要求：所有时间字段的返回格式必须为：YYYY-MM-DD HH:MM:SS（例如：2006-01-02 15:04:05）。" +
 				"明确时间：如果用户提供了明确的时间（例如：明天下午三点、下周五上午十点），直接返回相应的 start_date 和 due_date。" +
 				"模糊时间和时间段：" +
 				"“今天”：即当前日期，start_date 为当天的00:00，due_date 为当天的23:59。\n“明天”：即明天的日期，start_date 为明天的00:00，due_date 为明天的23:59。\n“后天”：即后天的日期，start_date 为后天的00:00，due_date 为后天的23:59。\n“上午/中午/下午/晚上”：这些时间段应根据常规认知来设置。" +
-				"例如：\n上午：从 06:00 到 12:00；\n中午：从 12:00 到 14:00；\n下午：从 14:00 到 18:00；\n晚上：从 18:00 到 22:00；\n深夜/凌晨：从 22:00 到 06:00。\n“周一到周五的工作日”：如果没有指定具体日期，可以根据“工作日”的常识推测任务。例如：\n“周一到周五的上午”：从周一到周五的 06:00 到 12:00；\n“周一到周五的下午”：从周一到周五的 12:00 到 18:00。\n时间段与重复性任务：\n\n如果用户提到一个时间段（例如：“明天从下午三点到五点有个会议”），应创建两个任务：\n任务1：start_date 为明天下午3点，due_date 为明天下午5点。\n如果用户提到重复性任务（例如：“我每周三都开会”），应按周期设置任务。\n不确定时间：\n\n如果用户只说了大概的时间（例如：“下午开会”），则默认返回该时间段（如：14:00 到 18:00）。\n如果用户提到的是模糊时间点（例如：“下周某天开会”），则可以推测出合理的时间范围，默认设置任务时间为该日的00:00到23:59。\n注意事项：\n时间段：尽量遵循常见的社会和文化认知，推测合理的时间范围。\n时区处理：确保所有时间都以用户本地时区为准，自动转换时区差异。\n时间上的模糊性：当用户没有明确指定具体时间时，尽量根据常识和常规习惯给出合理的时间段。\n" +
+				"例如：\n上午：从 06:00 到 12:00；\n中午：从 12:00 到 14:00；\n下午：从 14:00 到 18:00；\n晚上：从 18:00 到 22:00；\n深夜/凌晨：从 22:00 到 06:00。\n“周一到周五的工作日”：如果没有指定具体日期，可以根据“工作日”的常识推测任务。例如：\n“周一到周五的上午”：从周一到周五的 06:00 到 12:00；\n“周一到周五的下午”：从周一到周五的 12:00 到 18:00。\n时间段与重复性任务：\n\n如果用户提到一个时间段（例如：“明天从下午三点到五点有个会议”），应创建两个任务：\n任务1：start_date 为明天下午3点，due_date 为明天下午5点。\n如果用户提到重复性任务（例如：“我每周三都开会”），只创建一个任务，并在 recurrence 字段中填写 RRULE 重复规则（例如：FREQ=WEEKLY;BYDAY=WE），start_date 和 due_date 为第一次发生的时间，不要拆分成多个任务。\n不确定时间：\n\n如果用户只说了大概的时间（例如：“下午开会”），则默认返回该时间段（如：14:00 到 18:00）。\n如果用户提到的是模糊时间点（例如：“下周某天开会”），则可以推测出合理的时间范围，默认设置任务时间为该日的00:00到23:59。\n注意事项：\n时间段：尽量遵循常见的社会和文化认知，推测合理的时间范围。\n时区处理：确保所有时间都以用户本地时区为准，自动转换时区差异。\n时间上的模糊性：当用户没有明确指定具体时间时，尽量根据常识和常规习惯给出合理的时间段。\n" +
 				"另外对于task的描述中的任务标题title字段，不要带时间和地点描述的字段",
 		},
 		{
@@ -55,18 +70,36 @@ func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, err
 
 	// 工具函数映射表
 	functionMapper := map[string]ToolFunction{
-		"CreateTask": func(args map[string]interface{}) (interface{}, error) {
-			return adaptCreateTask(userID, args) // ✅ 闭包传递 userID
+		"CreateTask": func(args map[string]interface{}) ([]dto.BulkOperation, error) {
+			return single(adaptCreateTask(userID, args)) // ✅ 闭包传递 userID
+		},
+		"UpdateTask": func(args map[string]interface{}) ([]dto.BulkOperation, error) {
+			return single(adaptUpdateTask(userID, args))
+		},
+		"DeleteTask": func(args map[string]interface{}) ([]dto.BulkOperation, error) {
+			return single(adaptDeleteTask(userID, args))
+		},
+		"InstantiateTemplate": func(args map[string]interface{}) ([]dto.BulkOperation, error) {
+			return adaptInstantiateTemplate(userID, args)
 		},
-		"UpdateTask": adaptUpdateTask,
-		"DeleteTask": adaptDeleteTask,
 	}
 
 	//添加for循环但是，会多次调用大模型损失效率
 	//for {
-	completion, err := FunctionCalling(messages)
+	// 工具参数中的类别取自用户自定义的类别
+	batch := AIBatch{ID: uuid.NewString()}
+	categories, err := services.CategoryNames(userID)
 	if err != nil {
-		return nil, err
+		return nil, batch, err
+	}
+	// 用户已有的模板名称作为实例化模板工具的可选值
+	templates, err := services.TemplateNames(userID)
+	if err != nil {
+		return nil, batch, err
+	}
+	completion, err := FunctionCalling(messages, categories, templates)
+	if err != nil {
+		return nil, batch, err
 	}
 
 	message := completion.Get("choices.0.message")
@@ -76,29 +109,21 @@ func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, err
 		//return message.Get("content"), nil
 		return append(messages, map[string]interface{}{
 			"content": message.Get("content").String(),
-		}), nil
+		}), batch, nil
 	}
 
 	// 添加助手的工具调用消息到上下文
 	assistantMsg := createAssistantMessage(message, toolCalls)
 	messages = append(messages, assistantMsg)
 
-	// 收集所有工具调用
-	for _, toolCall := range toolCalls.Array() {
-		response, err := processSingleToolCall(toolCall, functionMapper)
-		// 记录错误但继续处理
-		if err != nil {
-			response = map[string]interface{}{
-				"role":                    "tool",
-				"content":                 err.Error(),
-				"tool_call_function_name": toolCall.Get("function.name").String(),
-				"is_error":                false,
-			}
-		}
-
+	// 收集所有工具调用并在同一事务中执行，一份通知中的多个任务要么全部生效要么全部不生效
+	// 本次会话的全部修改记为同一个 AI 操作者，便于整体撤销
+	opts := services.WriteOptions{Actor: models.AIActor(batch.ID)}
+	responses, committed := applyToolCalls(userID, toolCalls.Array(), functionMapper, opts)
+	batch.Committed = committed
+	for _, response := range responses {
 		messages = append(messages, response)
 		logToolResponse(response)
-
 	}
 
 	messages = append(messages, map[string]interface{}{
@@ -116,7 +141,7 @@ func ProcessTaskWithAI(userID uint, input string) ([]map[string]interface{}, err
 	}
 
 	return finalCompletion.Get("choices.0.message.content").String(), nil*/
-	return messages, nil
+	return messages, batch, nil
 }
 
 //}
@@ -142,41 +167,123 @@ func createAssistantMessage(message gjson.Result, toolCalls gjson.Result) map[st
 	return msg
 }
 
-func processSingleToolCall(toolCall gjson.Result, mapper map[string]ToolFunction) (map[string]interface{}, error) {
+// applyToolCalls 将全部工具调用转换为批量操作并以原子方式执行，按调用顺序返回每个调用的工具消息
+// 任一调用无法解析或执行失败时，整批修改都不会生效，第二个返回值表示修改是否已提交
+func applyToolCalls(userID uint, toolCalls []gjson.Result, mapper map[string]ToolFunction, opts services.WriteOptions) ([]map[string]interface{}, bool) {
+	responses := make([]map[string]interface{}, len(toolCalls))
+	ops := make([]dto.BulkOperation, 0, len(toolCalls))
+	// 第 i 个工具调用对应 ops[starts[i]:starts[i+1]]
+	starts := make([]int, len(toolCalls)+1)
+	parsed := true
+	for i, toolCall := range toolCalls {
+		starts[i] = len(ops)
+		callOps, err := processSingleToolCall(userID, toolCall, mapper)
+		if err != nil {
+			responses[i], _ = buildErrorResponse(toolCall, "%v", err)
+			parsed = false
+			continue
+		}
+		ops = append(ops, callOps...)
+	}
+	starts[len(toolCalls)] = len(ops)
+
+	var outcomes []services.BulkOutcome
+	committed := false
+	if parsed {
+		var err error
+		outcomes, committed, err = services.BulkApply(userID, services.BulkAtomic, ops, opts)
+		if err != nil {
+			for i, toolCall := range toolCalls {
+				responses[i], _ = buildErrorResponse(toolCall, "执行失败: %v", err)
+			}
+			return responses, false
+		}
+	}
+
+	for i, toolCall := range toolCalls {
+		if responses[i] != nil {
+			continue
+		}
+		if outcomes == nil {
+			responses[i], _ = buildErrorResponse(toolCall, "执行失败: %v", services.ErrBulkRolledBack)
+			continue
+		}
+		callOutcomes := outcomes[starts[i]:starts[i+1]]
+		if err := firstOutcomeError(callOutcomes); err != nil {
+			responses[i], _ = buildErrorResponse(toolCall, "执行失败: %v", err)
+			continue
+		}
+		var content interface{}
+		if len(callOutcomes) == 1 {
+			content = callOutcomes[0].Task
+		} else {
+			tasks := make([]*models.Task, len(callOutcomes))
+			for j, outcome := range callOutcomes {
+				tasks[j] = outcome.Task
+			}
+			content = tasks
+		}
+		responses[i] = map[string]interface{}{
+			"role":                    "tool",
+			"content":                 fmt.Sprintf("%v", content),
+			"tool_call_function_name": toolCall.Get("function.name").String(),
+		}
+	}
+	return responses, committed
+}
+
+// firstOutcomeError 返回一组操作结果中最先出现的错误，优先返回导致回滚的错误
+func firstOutcomeError(outcomes []services.BulkOutcome) error {
+	var rolledBack error
+	for _, outcome := range outcomes {
+		if outcome.Err == nil {
+			continue
+		}
+		if !errors.Is(outcome.Err, services.ErrBulkRolledBack) {
+			return outcome.Err
+		}
+		if rolledBack == nil {
+			rolledBack = outcome.Err
+		}
+	}
+	return rolledBack
+}
+
+// single 将只对应一个操作的适配器结果转换为操作列表
+func single(op dto.BulkOperation, err error) ([]dto.BulkOperation, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []dto.BulkOperation{op}, nil
+}
+
+// processSingleToolCall 解析单个工具调用，返回对应的批量操作
+func processSingleToolCall(userID uint, toolCall gjson.Result, mapper map[string]ToolFunction) ([]dto.BulkOperation, error) {
 	functionName := toolCall.Get("function.name").String()
 	argumentsString := toolCall.Get("function.arguments").Str
 
 	// 参数解析
 	var arguments map[string]interface{}
 	if err := json.Unmarshal([]byte(argumentsString), &arguments); err != nil {
-		return buildErrorResponse(toolCall, "参数解析失败: %v", err)
+		return nil, fmt.Errorf("参数解析失败: %v", err)
 	}
 
-	// 任务检索处理
+	// 任务检索处理，只在当前用户的任务中检索
 	if needsTaskLookup(functionName) {
-		taskID, err := searchTask(arguments)
+		task, err := searchTask(userID, arguments)
 		if err != nil {
-			return buildErrorResponse(toolCall, "任务查找失败: %v", err)
+			return nil, fmt.Errorf("任务查找失败: %v", err)
 		}
-		arguments["id"] = taskID
+		arguments["id"] = fmt.Sprintf("%d", task.ID)
+		arguments["version"] = task.Version
 	}
 
-	// 执行目标函数
+	// 转换为批量操作
 	function, exists := mapper[functionName]
 	if !exists {
-		return buildErrorResponse(toolCall, "未知函数: %s", functionName)
-	}
-
-	output, err := function(arguments)
-	if err != nil {
-		return buildErrorResponse(toolCall, "执行失败: %v", err)
+		return nil, fmt.Errorf("未知函数: %s", functionName)
 	}
-
-	return map[string]interface{}{
-		"role":                    "tool",
-		"content":                 fmt.Sprintf("%v", output),
-		"tool_call_function_name": toolCall.Get("function.name").String(),
-	}, nil
+	return function(arguments)
 }
 
 // 构建错误响应
@@ -2,6 +2,7 @@ package ai_service
 
 import (
 	"AITodo/dto"
+	"AITodo/models"
 	"bufio"
 	"bytes"
 	"encoding/json"
@@ -28,10 +29,74 @@ var client = &http.Client{
 	Transport: transport,
 }
 
-// functionCalling 发送请求并解析 DeepSeek API 响应
-func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
-	// 定义工具列表
-	var tools = []map[string]interface{}{
+// functionCalling 发送请求并解析 DeepSeek API 响应，categories 为用户的类别，作为工具参数中类别的可选值，
+// templates 为用户的模板名称，作为实例化模板工具的可选值
+func FunctionCalling(messages []map[string]interface{}, categories, templates []string) (gjson.Result, error) {
+	// 构造请求体
+	requestBody := dto.RequestBody{
+		Model:             "qwen-plus",
+		Messages:          messages,
+		Tools:             taskTools(categories, templates),
+		ParallelToolCalls: true,
+	}
+
+	// 将请求体转为 JSON
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	// 创建 POST 请求
+	req, err := http.NewRequest("POST", "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// 设置请求头
+	apiKey := os.Getenv("DASHSCOPE_API_KEY")
+	if apiKey == "" {
+		return gjson.Result{}, fmt.Errorf("API key is missing")
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	// 发送请求
+	resp, err := client.Do(req)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 检查响应状态
+	if resp.StatusCode != http.StatusOK {
+		bodyText, _ := io.ReadAll(resp.Body)
+		return gjson.Result{}, fmt.Errorf("API Error: %s\nResponse: %s", resp.Status, string(bodyText))
+	}
+
+	// 读取响应体
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// 使用 gjson 解析响应体
+	completion := gjson.ParseBytes(bodyBytes)
+	if !completion.Exists() {
+		return gjson.Result{}, fmt.Errorf("failed to parse response body")
+	}
+
+	// 提取 choices 数组
+	choices := completion.Get("choices").Array()
+	if len(choices) == 0 {
+		return gjson.Result{}, fmt.Errorf("no response from AI")
+	}
+
+	return completion, nil
+}
+
+// taskTools 生成任务管理的工具列表，类别的可选值由用户的类别决定，用户没有模板时不提供实例化模板工具
+func taskTools(categories, templates []string) []map[string]interface{} {
+	tools := []map[string]interface{}{
 		{
 			"type": "function",
 			"function": map[string]interface{}{
@@ -62,7 +127,8 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"category": map[string]interface{}{
 									"type":        "string",
-									"description": "任务类别，从{工作、学习、生活、健身、其他}这四个标签中选择一个，如果无法判断则默认选择其他，必填",
+									"enum":        categories,
+									"description": "任务类别，从可选值中选择一个，如果无法判断则选择" + models.DefaultCategoryName + "，必填",
 								},
 								"location": map[string]interface{}{
 									"type":        "string",
@@ -74,8 +140,14 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"status": map[string]interface{}{
 									"type":        "string",
+									"enum":        []string{"pending", "in_progress", "completed", "failed"},
 									"description": "任务状态，默认为'pending'，可选",
 								},
+								"priority": map[string]interface{}{
+									"type":        "string",
+									"enum":        []string{"P0", "P1", "P2", "P3"},
+									"description": "优先级，根据用户的措辞推断：“紧急”“马上”“立刻”“今天必须”为P0；“重要”“优先”为P1；没有提到为P2；“不着急”“有空再说”“随便什么时候”为P3，可选",
+								},
 								"start_date": map[string]interface{}{
 									"type":        "string",
 									"description": "任务开始日期，必须按照如下示例格式填写：2006-01-02 15:04:05,必填",
@@ -84,6 +156,28 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 									"type":        "string",
 									"description": "任务截止日期，必须按照如下示例格式填写：2006-01-02 15:04:05，必填",
 								},
+								"recurrence": map[string]interface{}{
+									"type":        "string",
+									"description": "重复规则，RFC 5545 RRULE 格式，例如每周三为 FREQ=WEEKLY;BYDAY=WE，每天为 FREQ=DAILY，可用 COUNT 或 UNTIL（如 UNTIL=20261231T235959Z）限定结束，可另起一行写 EXDATE 排除某些日期。start_date 和 due_date 填写第一次发生的时间。仅在用户描述的是重复性任务时填写，可选",
+								},
+								"tags": map[string]interface{}{
+									"type":        "array",
+									"items":       map[string]interface{}{"type": "string"},
+									"description": "标签列表，从用户的描述中提取简短的关键词作为标签（例如“紧急”“等待回复”），每个标签不含空格，用户没有提到时不填，可选",
+								},
+								"estimate_minutes": map[string]interface{}{
+									"type":        "integer",
+									"description": "预计用时（分钟），仅在用户提到需要多久时填写（例如“大概两个小时”填写120），可选",
+								},
+								"project": map[string]interface{}{
+									"type":        "string",
+									"description": "任务所属项目（清单）的名称，仅在用户明确提到要把任务放到某个项目或清单里时填写（例如“把这个加到毕业设计里”填写“毕业设计”），项目不存在时会自动创建，可选",
+								},
+								"checklist": map[string]interface{}{
+									"type":        "array",
+									"items":       map[string]interface{}{"type": "string"},
+									"description": "任务的检查项（子步骤）列表，按原文顺序填写。当用户的输入是列举多个事项的清单式通知时填写，例如“买：牛奶、鸡蛋、面包”应创建标题为“买东西”的任务，检查项为 [\"牛奶\", \"鸡蛋\", \"面包\"]；只有一件事时不填，可选",
+								},
 							},
 							"required": []string{"title", "due_date"},
 						},
@@ -103,7 +197,7 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 					"properties": map[string]interface{}{
 						"task": map[string]interface{}{
 							"type":        "*models.Task",
-							"description": "任务对象指针，包含以下可更新字段，在提供符合用户描述的字段值时，需结合历史对话中的任务对象。如果发现当前任务与历史任务相关且字段值已存在，请尽量保持字段值与之前一致。",
+							"description": "任务对象指针，包含以下可更新字段。title 用于定位要修改的任务，不会修改任务标题，重命名请填写 new_title，其余字段只填写用户明确要求修改的部分，未提及的字段不要返回，以免覆盖原有内容。",
 							"properties": map[string]interface{}{
 								"id": map[string]interface{}{
 									"type":        "uint",
@@ -111,11 +205,16 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"title": map[string]interface{}{
 									"type":        "string",
-									"description": "任务标题，不要带时间和地点描述的字段，长度不超过255字符，必填",
+									"description": "任务标题，用于定位要修改的任务，不要带时间和地点描述的字段，长度不超过255字符，必填",
+								},
+								"new_title": map[string]interface{}{
+									"type":        "string",
+									"description": "修改后的任务标题，仅在用户明确要求重命名任务时填写，长度不超过255字符，可选",
 								},
 								"category": map[string]interface{}{
 									"type":        "string",
-									"description": "任务类别，从{工作、学习、生活、健身、其他}这四个标签中选择一个，如果无法判断则默认选择其他，必填",
+									"enum":        categories,
+									"description": "任务类别，从可选值中选择一个，仅在需要修改时填写，可选",
 								},
 								"location": map[string]interface{}{
 									"type":        "string",
@@ -127,15 +226,34 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"status": map[string]interface{}{
 									"type":        "string",
-									"description": "任务状态，可选",
+									"enum":        []string{"pending", "in_progress", "completed", "failed"},
+									"description": "任务状态，已完成的任务只能重新打开为'pending'或'in_progress'，可选",
+								},
+								"priority": map[string]interface{}{
+									"type":        "string",
+									"enum":        []string{"P0", "P1", "P2", "P3"},
+									"description": "优先级，仅在用户表达了轻重缓急的变化时填写（例如“这个很急”为P0，“不着急了”为P3），可选",
 								},
 								"start_date": map[string]interface{}{
 									"type":        "string",
-									"description": "任务开始日期，必须按照如下示例格式填写：2006-01-02 15:04:05，必填",
+									"description": "任务开始日期，必须按照如下示例格式填写：2006-01-02 15:04:05，仅在需要修改时填写，可选",
 								},
 								"due_date": map[string]interface{}{
 									"type":        "string",
-									"description": "任务截止日期，必须按照如下示例格式填写：2006-01-02 15:04:05，必填",
+									"description": "任务截止日期，必须按照如下示例格式填写：2006-01-02 15:04:05，仅在需要修改时填写，可选",
+								},
+								"recurrence": map[string]interface{}{
+									"type":        "string",
+									"description": "重复规则，RFC 5545 RRULE 格式，例如 FREQ=WEEKLY;BYDAY=WE，仅在需要修改重复规则时填写，可选",
+								},
+								"tags": map[string]interface{}{
+									"type":        "array",
+									"items":       map[string]interface{}{"type": "string"},
+									"description": "修改后的完整标签列表，会替换任务原有的标签，仅在用户要求添加、删除或修改标签时填写，可选",
+								},
+								"estimate_minutes": map[string]interface{}{
+									"type":        "integer",
+									"description": "预计用时（分钟），仅在用户要求修改预计用时时填写，可选",
 								},
 							},
 						},
@@ -148,7 +266,7 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 			"type": "function",
 			"function": map[string]interface{}{
 				"name":        "DeleteTask",
-				"description": "删除任务",
+				"description": "删除任务，任务会被移入回收站，可在保留期内恢复",
 				"parameters": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
@@ -166,7 +284,8 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"category": map[string]interface{}{
 									"type":        "string",
-									"description": "任务类别，从{工作、学习、生活、健身、其他}这四个标签中选择一个，如果无法判断则默认选择其他，必填",
+									"enum":        categories,
+									"description": "任务类别，从可选值中选择一个，如果无法判断则选择" + models.DefaultCategoryName + "，必填",
 								},
 								"location": map[string]interface{}{
 									"type":        "string",
@@ -178,6 +297,7 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 								},
 								"status": map[string]interface{}{
 									"type":        "string",
+									"enum":        []string{"pending", "in_progress", "completed", "failed"},
 									"description": "任务状态，默认为'pending'，可选",
 								},
 								"start_date": map[string]interface{}{
@@ -197,67 +317,31 @@ func FunctionCalling(messages []map[string]interface{}) (gjson.Result, error) {
 			},
 		},
 	}
-
-	// 构造请求体
-	requestBody := dto.RequestBody{
-		Model:             "qwen-plus",
-		Messages:          messages,
-		Tools:             tools,
-		ParallelToolCalls: true,
-	}
-
-	// 将请求体转为 JSON
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return gjson.Result{}, fmt.Errorf("failed to marshal request body: %w", err)
-	}
-
-	// 创建 POST 请求
-	req, err := http.NewRequest("POST", "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return gjson.Result{}, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// 设置请求头
-	apiKey := os.Getenv("DASHSCOPE_API_KEY")
-	if apiKey == "" {
-		return gjson.Result{}, fmt.Errorf("API key is missing")
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return gjson.Result{}, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		bodyText, _ := io.ReadAll(resp.Body)
-		return gjson.Result{}, fmt.Errorf("API Error: %s\nResponse: %s", resp.Status, string(bodyText))
-	}
-
-	// 读取响应体
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return gjson.Result{}, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// 使用 gjson 解析响应体
-	completion := gjson.ParseBytes(bodyBytes)
-	if !completion.Exists() {
-		return gjson.Result{}, fmt.Errorf("failed to parse response body")
-	}
-
-	// 提取 choices 数组
-	choices := completion.Get("choices").Array()
-	if len(choices) == 0 {
-		return gjson.Result{}, fmt.Errorf("no response from AI")
+	if len(templates) == 0 {
+		return tools
 	}
-
-	return completion, nil
+	return append(tools, map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        "InstantiateTemplate",
+			"description": "按用户已有的任务模板一次创建模板中的全部任务，任务的开始和截止时间按模板中相对锚点的偏移计算",
+			"parameters": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"enum":        templates,
+						"description": "模板名称，从可选值中选择与用户描述最匹配的一个，必填",
+					},
+					"anchor": map[string]interface{}{
+						"type":        "string",
+						"description": "实例化的基准时间，通常是用户所说的开始日期（例如“从下周一开始”填写下周一），必须按照如下示例格式填写：2006-01-02 00:00:00，用户没有提到时填写今天，必填",
+					},
+				},
+				"required": []string{"name", "anchor"},
+			},
+		},
+	})
 }
 
 // StreamFunctionCalling 流式处理AI响应并将其直接写入HTTP响应
@@ -23,23 +23,25 @@ const (
 	DateProximityThreshold = 7   // 日期邻近阈值（天）
 )
 
-func searchTask(input map[string]interface{}) (string, error) {
+// searchTask 在用户的任务中检索与模型给出的字段最匹配的任务，返回匹配到的任务，
+// 调用方以其版本号作为写入时的期望版本，避免修改到检索之后被改动过的任务
+func searchTask(userID uint, input map[string]interface{}) (*models.Task, error) {
 	taskInterface, err := parseTask(input)
 	if err != nil {
-		return "", fmt.Errorf("input解析失败: %v", err)
+		return nil, fmt.Errorf("input解析失败: %v", err)
 	}
 	task, ok := taskInterface.(models.Task)
 	if !ok {
-		return "", fmt.Errorf("任务数据解析失败，类型转换失败")
+		return nil, fmt.Errorf("任务数据解析失败，类型转换失败")
 	}
 
-	query := db.DB.Model(&models.Task{})
+	query := db.DB.Model(&models.Task{}).Scopes(models.OwnedBy(userID))
 	// 动态构建查询条件
-	buildQueryConditions(query, task)
+	query = buildQueryConditions(query, task)
 
 	var tasks []models.Task
 	if err := query.Order("created_at DESC").Find(&tasks).Error; err != nil {
-		return "", fmt.Errorf("数据库查询失败: %v", err)
+		return nil, fmt.Errorf("数据库查询失败: %v", err)
 	}
 
 	//添加对tasks的匹配
@@ -79,7 +81,7 @@ func buildQueryConditions(query *gorm.DB, task models.Task) *gorm.DB {
 }
 
 // 新增多匹配处理函数
-func handleMultipleMatches(tasks []models.Task, candidate models.Task) (string, error) {
+func handleMultipleMatches(tasks []models.Task, candidate models.Task) (*models.Task, error) {
 	scores := make(map[uint]float64)
 
 	for _, t := range tasks {
@@ -99,11 +101,11 @@ func handleMultipleMatches(tasks []models.Task, candidate models.Task) (string,
 
 	// 验证匹配质量
 	if maxScore < MinAcceptableScore {
-		return "", fmt.Errorf("找到%d个可能匹配，但均未达到匹配阈值（%.2f/%f）",
+		return nil, fmt.Errorf("找到%d个可能匹配，但均未达到匹配阈值（%.2f/%f）",
 			len(tasks), maxScore, MinAcceptableScore)
 	}
 
-	return fmt.Sprintf("%d", bestTask.ID), nil
+	return &bestTask, nil
 }
 
 // 综合评分计算函数
@@ -156,15 +158,15 @@ func KeywordCoverage(target, query string) float64 {
 }
 
 // 修改后的结果处理函数
-func handleSearchResults(tasks []models.Task) (string, error) {
+func handleSearchResults(tasks []models.Task) (*models.Task, error) {
 	switch len(tasks) {
 	case 0:
-		return "", errors.New("未找到匹配任务")
+		return nil, errors.New("未找到匹配任务")
 	case 1:
-		return fmt.Sprintf("%d", tasks[0].ID), nil
+		return &tasks[0], nil
 	default:
 		// 现在由handleMultipleMatches处理多结果情况
-		return "", errors.New("匹配结果处理异常")
+		return nil, errors.New("匹配结果处理异常")
 	}
 }
 
@@ -176,8 +178,8 @@ func parseTask(args map[string]interface{}) (interface{}, error) {
 	}
 
 	// 验证 title 和 due_date 是否存在
-	if err, ok := task["title"].(string); !ok {
-		return nil, fmt.Errorf("task字段确实或者格式错误:%w", err)
+	if _, ok := task["title"].(string); !ok {
+		return nil, fmt.Errorf("task字段缺失或者格式错误:title")
 	}
 
 	startDate, err := parseTime(task["start_date"])
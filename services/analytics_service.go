@@ -4,14 +4,57 @@ package services
 import (
 	"AITodo/db"
 	"AITodo/dto"
+	"AITodo/models"
+	"errors"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAnalyticsRange 统计的时间范围不合法
+var ErrInvalidAnalyticsRange = errors.New("统计时间范围不合法")
+
+// maxAnalyticsPeriods 按时间段统计时允许的最多时间段数量
+const maxAnalyticsPeriods = 1000
+
+// 统计的任务层级
+const (
+	TaskLevelAll  = "all"  // 全部任务
+	TaskLevelLeaf = "leaf" // 只统计没有子任务的叶子任务
+	TaskLevelTop  = "top"  // 只统计顶层任务
 )
 
-// GetTrendData 获取趋势数据
-func GetTrendData(userID uint, interval string, start, end time.Time) (*dto.TrendData, error) {
+// IsValidTaskLevel 判断统计层级是否合法
+func IsValidTaskLevel(level string) bool {
+	return level == TaskLevelAll || level == TaskLevelLeaf || level == TaskLevelTop
+}
+
+// analyticsTaskSource 返回参与统计的任务集合
+// 回收站中的任务不参与统计，projectID 不为 0 时只统计该项目的任务
+func analyticsTaskSource(level string, projectID uint) *gorm.DB {
+	source := db.DB.Table("tasks").Where("tasks.deleted_at IS NULL")
+	if projectID != 0 {
+		source = source.Where("tasks.project_id = ?", projectID)
+	}
+	switch level {
+	case TaskLevelLeaf:
+		source = source.Where("NOT EXISTS (SELECT 1 FROM tasks AS children WHERE children.parent_id = tasks.id AND children.deleted_at IS NULL)")
+	case TaskLevelTop:
+		source = source.Where("tasks.parent_id IS NULL")
+	}
+	return source
+}
+
+// analyticsTasks 以按层级和项目筛选后的任务集合作为 tasks 表构造统计查询，所有统计查询都应从这里开始
+func analyticsTasks(level string, projectID uint) *gorm.DB {
+	return db.DB.Table("(?) AS tasks", analyticsTaskSource(level, projectID))
+}
+
+// GetTrendData 获取趋势数据，level 决定统计全部、叶子或顶层任务，projectID 为 0 表示不限项目
+func GetTrendData(userID uint, interval, level string, projectID uint, start, end time.Time) (*dto.TrendData, error) {
 	// 执行数据库查询
-	rawData, err := fetchTrendDataFromDB(userID, interval, start, end)
+	rawData, err := fetchTrendDataFromDB(userID, interval, level, projectID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -34,11 +77,13 @@ func GetTrendData(userID uint, interval string, start, end time.Time) (*dto.Tren
 		}
 
 		switch item.Status {
-		case "completed":
+		case models.TaskStatusCompleted:
 			result.Completed[index] += item.Count
-		case "pending":
+		case models.TaskStatusPending:
 			result.Pending[index] += item.Count
-		case "failed":
+		case models.TaskStatusInProgress:
+			result.Progress[index] += item.Count
+		case models.TaskStatusFailed:
 			result.Failed[index] += item.Count
 		}
 	}
@@ -47,7 +92,7 @@ func GetTrendData(userID uint, interval string, start, end time.Time) (*dto.Tren
 }
 
 // fetchTrendDataFromDB 从数据库中获取趋势数据
-func fetchTrendDataFromDB(userID uint, interval string, start, end time.Time) ([]struct {
+func fetchTrendDataFromDB(userID uint, interval, level string, projectID uint, start, end time.Time) ([]struct {
 	PeriodStart time.Time
 	Status      string
 	Count       int
@@ -58,62 +103,26 @@ func fetchTrendDataFromDB(userID uint, interval string, start, end time.Time) ([
 		Count       int
 	}
 
-	var query string
-
 	// 使用 DATE_FORMAT 确保返回 YYYY-MM-DD 格式的字符串
+	var period string
 	switch interval {
 	case "week":
-		query = `
-            SELECT 
-                DATE_FORMAT(DATE_SUB(created_at, INTERVAL WEEKDAY(created_at) DAY), '%Y-%m-%d') AS period_start,
-                status,
-                COUNT(*) AS count
-            FROM tasks
-            WHERE 
-                user_id = ? AND
-                created_at BETWEEN ? AND ?
-            GROUP BY period_start, status
-            ORDER BY period_start`
+		period = "DATE_FORMAT(DATE_SUB(created_at, INTERVAL WEEKDAY(created_at) DAY), '%Y-%m-%d')"
 	case "month":
-		query = `
-            SELECT 
-                DATE_FORMAT(created_at, '%Y-%m-01') AS period_start,
-                status,
-                COUNT(*) AS count
-            FROM tasks
-            WHERE 
-                user_id = ? AND
-                created_at BETWEEN ? AND ?
-            GROUP BY period_start, status
-            ORDER BY period_start`
+		period = "DATE_FORMAT(created_at, '%Y-%m-01')"
 	case "year":
-		query = `
-            SELECT 
-                DATE_FORMAT(created_at, '%Y-01-01') AS period_start,
-                status,
-                COUNT(*) AS count
-            FROM tasks
-            WHERE 
-                user_id = ? AND
-                created_at BETWEEN ? AND ?
-            GROUP BY period_start, status
-            ORDER BY period_start`
+		period = "DATE_FORMAT(created_at, '%Y-01-01')"
 	default: // daily
-		query = `
-            SELECT 
-                DATE_FORMAT(created_at, '%Y-%m-%d') AS period_start,
-                status,
-                COUNT(*) AS count
-            FROM tasks
-            WHERE 
-                user_id = ? AND
-                created_at BETWEEN ? AND ?
-            GROUP BY period_start, status
-            ORDER BY period_start`
+		period = "DATE_FORMAT(created_at, '%Y-%m-%d')"
 	}
 
 	// 执行查询
-	err := db.DB.Raw(query, userID, start, end).Scan(&result).Error
+	err := analyticsTasks(level, projectID).
+		Select(period+" AS period_start, status, COUNT(*) AS count").
+		Where("user_id = ? AND created_at BETWEEN ? AND ?", userID, start, end).
+		Group("period_start, status").
+		Order("period_start").
+		Scan(&result).Error
 	if err != nil {
 		return nil, err
 	}
@@ -177,43 +186,47 @@ func calculatePeriodIndex(start, current time.Time, interval string) int {
 	}
 }
 
-func GetCategoryDistribution(userID uint, interval string, start, end time.Time) (*dto.CategoryDistribution, error) {
+// GetCategoryDistribution 按用户的类别统计任务数量，颜色取自类别设置
+// 类别已被删除但仍有任务引用的名称以默认颜色追加在末尾，避免统计被丢弃，回收站中的任务不参与统计
+func GetCategoryDistribution(userID uint, interval, level string, projectID uint, start, end time.Time) (*dto.CategoryDistributionResponse, error) {
 	// 执行数据库查询
-	rawData, err := fetchCategoryDataFromDB(userID, interval, start, end)
+	rawData, err := fetchCategoryDataFromDB(userID, interval, level, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := models.ListCategories(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 初始化结果集
-	result := &dto.CategoryDistribution{
-		Work:    0,
-		Study:   0,
-		Life:    0,
-		Fitness: 0,
-		Other:   0,
+	result := &dto.CategoryDistributionResponse{Categories: make([]dto.CategoryData, 0, len(categories))}
+	index := make(map[string]int, len(categories))
+	for _, c := range categories {
+		color := c.Color
+		if color == "" {
+			color = dto.DefaultCategoryColor
+		}
+		index[c.Name] = len(result.Categories)
+		result.Categories = append(result.Categories, dto.CategoryData{Name: c.Name, Color: color})
 	}
 
 	// 填充数据
 	for _, item := range rawData {
-		switch item.Category {
-		case "工作":
-			result.Work += item.Count
-		case "学习":
-			result.Study += item.Count
-		case "生活":
-			result.Life += item.Count
-		case "健身":
-			result.Fitness += item.Count
-		case "其他":
-			result.Other += item.Count
+		i, ok := index[item.Category]
+		if !ok {
+			i = len(result.Categories)
+			index[item.Category] = i
+			result.Categories = append(result.Categories, dto.CategoryData{Name: item.Category, Color: dto.DefaultCategoryColor})
 		}
+		result.Categories[i].Value += item.Count
 	}
 
 	return result, nil
 }
 
 // 数据库查询实现
-func fetchCategoryDataFromDB(userID uint, interval string, start, end time.Time) ([]struct {
+func fetchCategoryDataFromDB(userID uint, interval, level string, projectID uint, start, end time.Time) ([]struct {
 	Category string
 	Count    int
 }, error) {
@@ -222,58 +235,61 @@ func fetchCategoryDataFromDB(userID uint, interval string, start, end time.Time)
 		Count    int
 	}
 
-	var query string
-
-	switch interval {
-	case "week":
-		query = `
-			SELECT 
-				category,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY category`
-	case "month":
-		query = `
-			SELECT 
-				category,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY category`
-	case "year":
-		query = `
-			SELECT 
-				category,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY category`
-	default:
-		query = `
-			SELECT 
-				category,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY category`
-	}
-
-	err := db.DB.Raw(query, userID, start, end).Scan(&result).Error
+	err := analyticsTasks(level, projectID).
+		Select("category, COUNT(*) AS count").
+		Where("user_id = ? AND created_at BETWEEN ? AND ?", userID, start, end).
+		Group("category").
+		Scan(&result).Error
 	return result, err
 }
 
-func GetHeatmapData(userID uint, interval string, start, end time.Time) ([]dto.TimeSlot, error) {
+// GetTagDistribution 按标签统计任务数量及各状态的数量，同一任务的多个标签分别计入
+func GetTagDistribution(userID uint, level string, projectID uint, start, end time.Time) ([]dto.TagStat, error) {
+	var rows []struct {
+		Tag    string
+		Status string
+		Count  int
+	}
+	err := analyticsTasks(level, projectID).
+		Select("tags.name AS tag, tasks.status AS status, COUNT(*) AS count").
+		Joins("JOIN task_tags ON task_tags.task_id = tasks.id").
+		Joins("JOIN tags ON tags.id = task_tags.tag_id").
+		Where("tasks.user_id = ? AND tasks.created_at BETWEEN ? AND ?", userID, start, end).
+		Group("tags.name, tasks.status").
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := []dto.TagStat{}
+	index := map[string]int{}
+	for _, row := range rows {
+		i, ok := index[row.Tag]
+		if !ok {
+			i = len(result)
+			index[row.Tag] = i
+			result = append(result, dto.TagStat{Tag: row.Tag})
+		}
+		stat := &result[i]
+		stat.Total += row.Count
+		switch row.Status {
+		case models.TaskStatusCompleted:
+			stat.Completed += row.Count
+		case models.TaskStatusPending:
+			stat.Pending += row.Count
+		case models.TaskStatusInProgress:
+			stat.Progress += row.Count
+		case models.TaskStatusFailed:
+			stat.Failed += row.Count
+		}
+	}
+	return result, nil
+}
+
+func GetHeatmapData(userID uint, interval, level string, projectID uint, start, end time.Time) ([]dto.TimeSlot, error) {
 	// 执行数据库查询
-	rawData, err := fetchHeatmapDataFromDB(userID, interval, start, end)
+	rawData, err := fetchHeatmapDataFromDB(userID, interval, level, projectID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -282,58 +298,328 @@ func GetHeatmapData(userID uint, interval string, start, end time.Time) ([]dto.T
 }
 
 // 数据库查询实现
-func fetchHeatmapDataFromDB(userID uint, interval string, start, end time.Time) ([]dto.TimeSlot, error) {
+func fetchHeatmapDataFromDB(userID uint, interval, level string, projectID uint, start, end time.Time) ([]dto.TimeSlot, error) {
 	var result []dto.TimeSlot
 
-	var query string
+	err := analyticsTasks(level, projectID).
+		Select("DATE_FORMAT(created_at, '%H:%i') AS time, COUNT(*) AS count").
+		Where("user_id = ? AND created_at BETWEEN ? AND ?", userID, start, end).
+		Group("time").
+		Order("time").
+		Scan(&result).Error
+	return result, err
+}
+
+// GetTimeTracking 按类别和时间段对比预计用时与实际计时，统计范围为 start 当天零点至 end 当天结束
+// 预计用时按任务的截止时间归入时间段，实际用时按计时记录的开始时间归入时间段，运行中的计时器计算到当前
+func GetTimeTracking(userID uint, interval, level string, projectID uint, start, end time.Time) (*dto.TimeTrackingResponse, error) {
+	periods, err := newAnalyticsPeriods(interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// 按秒累计，最后统一换算为分钟，避免逐条取整带来的误差
+	periodEstimate := make([]int64, len(periods.starts))
+	periodTracked := make([]int64, len(periods.starts))
+
+	var estimates []struct {
+		Category        string
+		DueDate         time.Time
+		EstimateMinutes int
+	}
+	err = analyticsTasks(level, projectID).
+		Select("category, due_date, estimate_minutes").
+		Where("user_id = ? AND estimate_minutes > 0 AND due_date >= ? AND due_date < ?", userID, start, periods.until).
+		Scan(&estimates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		Category  string
+		StartedAt time.Time
+		EndedAt   *time.Time
+		Seconds   int64
+	}
+	err = analyticsTasks(level, projectID).
+		Select("tasks.category AS category, time_entries.started_at AS started_at, time_entries.ended_at AS ended_at, time_entries.seconds AS seconds").
+		Joins("JOIN time_entries ON time_entries.task_id = tasks.id").
+		Where("time_entries.user_id = ? AND time_entries.started_at >= ? AND time_entries.started_at < ?", userID, start, periods.until).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := models.ListCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(categories))
+	colors := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names = append(names, c.Name)
+		colors[c.Name] = c.Color
+	}
+	categoryEstimate := map[string]int64{}
+	categoryTracked := map[string]int64{}
+	addCategory := func(name string) {
+		if _, ok := colors[name]; !ok {
+			names = append(names, name)
+			colors[name] = ""
+		}
+	}
+
+	for _, item := range estimates {
+		addCategory(item.Category)
+		seconds := int64(item.EstimateMinutes) * 60
+		categoryEstimate[item.Category] += seconds
+		if i, ok := periods.indexOf(item.DueDate); ok {
+			periodEstimate[i] += seconds
+		}
+	}
+	now := time.Now()
+	for _, item := range entries {
+		addCategory(item.Category)
+		seconds := item.Seconds
+		if item.EndedAt == nil {
+			seconds = int64(now.Sub(item.StartedAt) / time.Second)
+		}
+		categoryTracked[item.Category] += seconds
+		if i, ok := periods.indexOf(item.StartedAt); ok {
+			periodTracked[i] += seconds
+		}
+	}
+
+	result := &dto.TimeTrackingResponse{
+		TimeRange:  periods.interval,
+		Categories: make([]dto.CategoryTimeStat, 0, len(names)),
+		Periods:    make([]dto.PeriodTimeStat, len(periods.starts)),
+	}
+	var totalEstimate, totalTracked int64
+	for _, name := range names {
+		color := colors[name]
+		if color == "" {
+			color = dto.DefaultCategoryColor
+		}
+		result.Categories = append(result.Categories, dto.CategoryTimeStat{
+			Name:            name,
+			Color:           color,
+			EstimateMinutes: secondsToMinutes(categoryEstimate[name]),
+			TrackedMinutes:  secondsToMinutes(categoryTracked[name]),
+		})
+		totalEstimate += categoryEstimate[name]
+		totalTracked += categoryTracked[name]
+	}
+	for i, label := range periods.labels() {
+		result.Periods[i] = dto.PeriodTimeStat{
+			Label:           label,
+			EstimateMinutes: secondsToMinutes(periodEstimate[i]),
+			TrackedMinutes:  secondsToMinutes(periodTracked[i]),
+		}
+	}
+	result.EstimateMinutes = secondsToMinutes(totalEstimate)
+	result.TrackedMinutes = secondsToMinutes(totalTracked)
+	return result, nil
+}
+
+// focusSegmentRow 参与统计的一段专注时间
+type focusSegmentRow struct {
+	StartedAt time.Time
+	EndedAt   time.Time
+	Seconds   int64
+	Completed bool
+}
+
+// fetchFocusSegments 获取开始时间在 [start, until) 内的专注时间
+func fetchFocusSegments(userID uint, level string, projectID uint, start, until time.Time) ([]focusSegmentRow, error) {
+	var rows []focusSegmentRow
+	err := analyticsTasks(level, projectID).
+		Select("focus_segments.started_at AS started_at, focus_segments.ended_at AS ended_at, focus_segments.seconds AS seconds, focus_segments.completed AS completed").
+		Joins("JOIN focus_segments ON focus_segments.task_id = tasks.id").
+		Where("focus_segments.user_id = ? AND focus_segments.started_at >= ? AND focus_segments.started_at < ?", userID, start, until).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetFocusHeatmap 统计番茄钟专注时长在一天 24 小时中的分布，跨越整点的专注时间按实际时长拆分到各小时
+// 统计范围为 start 当天零点至 end 当天结束，进行中的专注阶段在暂停、完成或结束后才计入
+func GetFocusHeatmap(userID uint, level string, projectID uint, start, end time.Time) ([]dto.FocusSlot, error) {
+	segments, err := fetchFocusSegments(userID, level, projectID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return focusSlots(segments), nil
+}
 
+// GetFocusStats 按时间段统计番茄钟的专注时长、完成数量和打断次数，并附带按小时的分布
+// 专注时长按开始时间、完成数量按完成时间、打断次数按会话开始时间归入时间段
+func GetFocusStats(userID uint, interval, level string, projectID uint, start, end time.Time) (*dto.FocusResponse, error) {
+	periods, err := newAnalyticsPeriods(interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := fetchFocusSegments(userID, level, projectID, start, periods.until)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []struct {
+		StartedAt     time.Time
+		Interruptions int
+	}
+	err = analyticsTasks(level, projectID).
+		Select("focus_sessions.started_at AS started_at, focus_sessions.interruptions AS interruptions").
+		Joins("JOIN focus_sessions ON focus_sessions.task_id = tasks.id").
+		Where("focus_sessions.user_id = ? AND focus_sessions.interruptions > 0 AND focus_sessions.started_at >= ? AND focus_sessions.started_at < ?", userID, start, periods.until).
+		Scan(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.FocusResponse{
+		TimeRange:  periods.interval,
+		Periods:    make([]dto.PeriodFocusStat, len(periods.starts)),
+		FocusSlots: focusSlots(segments),
+	}
+	for i, label := range periods.labels() {
+		result.Periods[i].Label = label
+	}
+	periodSeconds := make([]int64, len(periods.starts))
+	var totalSeconds int64
+	for _, segment := range segments {
+		if i, ok := periods.indexOf(segment.StartedAt); ok {
+			periodSeconds[i] += segment.Seconds
+		}
+		totalSeconds += segment.Seconds
+		if !segment.Completed {
+			continue
+		}
+		if i, ok := periods.indexOf(segment.EndedAt); ok {
+			result.Periods[i].Pomodoros++
+		}
+		result.Pomodoros++
+	}
+	for _, session := range sessions {
+		if i, ok := periods.indexOf(session.StartedAt); ok {
+			result.Periods[i].Interruptions += session.Interruptions
+		}
+		result.Interruptions += session.Interruptions
+	}
+	for i := range result.Periods {
+		result.Periods[i].FocusMinutes = secondsToMinutes(periodSeconds[i])
+	}
+	result.FocusMinutes = secondsToMinutes(totalSeconds)
+	return result, nil
+}
+
+// focusSlots 将专注时间按本地时间的小时拆分累计
+func focusSlots(segments []focusSegmentRow) []dto.FocusSlot {
+	var seconds [24]int64
+	for _, segment := range segments {
+		from := segment.StartedAt.In(time.Local)
+		to := segment.EndedAt.In(time.Local)
+		for from.Before(to) {
+			next := time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), 0, 0, 0, from.Location()).Add(time.Hour)
+			if next.After(to) {
+				next = to
+			}
+			seconds[from.Hour()] += int64(next.Sub(from) / time.Second)
+			from = next
+		}
+	}
+	slots := make([]dto.FocusSlot, 24)
+	for hour := range slots {
+		slots[hour] = dto.FocusSlot{Time: fmt.Sprintf("%02d:00", hour), Minutes: secondsToMinutes(seconds[hour])}
+	}
+	return slots
+}
+
+// analyticsPeriods 按 day/week/month/year 划分的统计时间段，覆盖 start 当天零点至 end 当天结束
+type analyticsPeriods struct {
+	interval string
+	starts   []time.Time
+	index    map[int64]int
+	until    time.Time // 统计范围的结束时间（不含）
+}
+
+// newAnalyticsPeriods 划分统计时间段，未知的 interval 按天划分
+func newAnalyticsPeriods(interval string, start, end time.Time) (*analyticsPeriods, error) {
+	switch interval {
+	case "week", "month", "year":
+	default:
+		interval = "day"
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w:end不能早于start", ErrInvalidAnalyticsRange)
+	}
+	p := &analyticsPeriods{interval: interval, index: map[int64]int{}, until: end.AddDate(0, 0, 1)}
+	for t := truncateToPeriod(start, interval); t.Before(p.until); t = nextPeriod(t, interval) {
+		if len(p.starts) >= maxAnalyticsPeriods {
+			return nil, fmt.Errorf("%w:时间段数量不能超过%d", ErrInvalidAnalyticsRange, maxAnalyticsPeriods)
+		}
+		p.index[t.Unix()] = len(p.starts)
+		p.starts = append(p.starts, t)
+	}
+	return p, nil
+}
+
+// indexOf 返回 t 所在时间段的下标
+func (p *analyticsPeriods) indexOf(t time.Time) (int, bool) {
+	i, ok := p.index[truncateToPeriod(t.In(p.until.Location()), p.interval).Unix()]
+	return i, ok
+}
+
+// labels 各时间段在图表中的标签
+func (p *analyticsPeriods) labels() []string {
+	labels := make([]string, len(p.starts))
+	for i, t := range p.starts {
+		labels[i] = periodLabel(t, p.interval)
+	}
+	return labels
+}
+
+// truncateToPeriod 返回 t 所在时间段的开始时间，周从周一开始
+func truncateToPeriod(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 	switch interval {
 	case "week":
-		query = `
-			SELECT 
-				DATE_FORMAT(created_at, '%H:%i') AS time,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY time
-			ORDER BY time`
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
 	case "month":
-		query = `
-			SELECT 
-				DATE_FORMAT(created_at, '%H:%i') AS time,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY time
-			ORDER BY time`
+		return day.AddDate(0, 0, 1-day.Day())
 	case "year":
-		query = `
-			SELECT 
-				DATE_FORMAT(created_at, '%H:%i') AS time,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY time
-			ORDER BY time`
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
 	default:
-		query = `
-			SELECT 
-				DATE_FORMAT(created_at, '%H:%i') AS time,
-				COUNT(*) AS count
-			FROM tasks
-			WHERE 
-				user_id = ? AND
-				created_at BETWEEN ? AND ?
-			GROUP BY time
-			ORDER BY time`
-	}
-
-	err := db.DB.Raw(query, userID, start, end).Scan(&result).Error
-	return result, err
+		return day
+	}
+}
+
+// nextPeriod 返回下一个时间段的开始时间
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// periodLabel 时间段在图表中的标签
+func periodLabel(t time.Time, interval string) string {
+	switch interval {
+	case "month":
+		return t.Format("2006-01")
+	case "year":
+		return t.Format("2006")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// secondsToMinutes 将秒数四舍五入为分钟
+func secondsToMinutes(seconds int64) int {
+	return int((seconds + 30) / 60)
 }
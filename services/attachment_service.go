@@ -0,0 +1,203 @@
+package services
+
+import (
+	"AITodo/config"
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"AITodo/util"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 附件相关错误
+var (
+	ErrAttachmentTooLarge  = errors.New("附件超过大小限制")
+	ErrUnsupportedFileType = errors.New("不支持的文件类型")
+	ErrQuotaExceeded       = errors.New("附件存储空间不足")
+	ErrInvalidDownloadURL  = errors.New("下载链接无效或已过期")
+)
+
+// sniffLen 识别文件类型需要读取的字节数
+const sniffLen = 512
+
+// ListAttachments 获取任务的附件，附带临时下载地址
+func ListAttachments(userID, taskID uint) ([]models.Attachment, error) {
+	if _, err := models.GetTaskById(userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	attachments, err := models.ListAttachments(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取附件失败:%w", err)
+	}
+	expires := time.Now().Add(config.Cfg.Storage.URLTTL)
+	for i := range attachments {
+		attachments[i].DownloadURL = util.SignDownloadURL(attachments[i].ID, expires)
+	}
+	return attachments, nil
+}
+
+// UploadAttachment 为任务上传附件，文件类型根据内容识别而不是客户端声明的类型
+// 文件先写入存储后端，再在事务中检查用户配额并创建记录，超出配额时删除已写入的文件
+func UploadAttachment(userID, taskID uint, fileName string, r io.Reader, size int64) (*models.Attachment, error) {
+	cfg := config.Cfg.Storage
+	if size > cfg.MaxFileSize {
+		return nil, fmt.Errorf("%w:单个附件不能超过 %d 字节", ErrAttachmentTooLarge, cfg.MaxFileSize)
+	}
+	if _, err := models.GetTaskById(userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	used, err := models.AttachmentUsageTx(db.DB, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("获取附件用量失败:%w", err)
+	}
+	if used+size > cfg.UserQuota {
+		return nil, quotaError(used, cfg.UserQuota)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("读取附件失败:%w", err)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !isAllowedType(contentType, cfg.AllowedTypes) {
+		return nil, fmt.Errorf("%w:%s", ErrUnsupportedFileType, contentType)
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UserID:      userID,
+		FileName:    normalizeFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  fmt.Sprintf("%d/%s", userID, uuid.NewString()),
+	}
+	body := io.MultiReader(bytes.NewReader(head[:n]), r)
+	if err := util.Storage.Put(attachment.StorageKey, body, size, contentType); err != nil {
+		return nil, fmt.Errorf("保存附件失败:%w", err)
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		used, err := models.AttachmentUsageTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取附件用量失败:%w", err)
+		}
+		if used+size > cfg.UserQuota {
+			return quotaError(used, cfg.UserQuota)
+		}
+		if err := models.CreateAttachmentTx(tx, attachment); err != nil {
+			return fmt.Errorf("保存附件失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		deleteStoredFiles([]string{attachment.StorageKey})
+		return nil, err
+	}
+	attachment.DownloadURL = util.SignDownloadURL(attachment.ID, time.Now().Add(cfg.URLTTL))
+	return attachment, nil
+}
+
+// DeleteAttachment 删除任务的附件
+func DeleteAttachment(userID, taskID, id uint) error {
+	var attachment *models.Attachment
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		var err error
+		if attachment, err = models.GetAttachmentTx(tx, userID, taskID, id); err != nil {
+			return fmt.Errorf("获取附件失败:%w", err)
+		}
+		return models.DeleteAttachmentTx(tx, attachment)
+	})
+	if err != nil {
+		return err
+	}
+	deleteStoredFiles([]string{attachment.StorageKey})
+	return nil
+}
+
+// OpenAttachment 校验下载链接并打开附件内容，调用方负责关闭返回的 io.ReadCloser
+func OpenAttachment(id uint, expires, sig string) (*models.Attachment, io.ReadCloser, error) {
+	if !util.VerifyDownloadURL(id, expires, sig, time.Now()) {
+		return nil, nil, ErrInvalidDownloadURL
+	}
+	attachment, err := models.GetAttachment(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取附件失败:%w", err)
+	}
+	content, err := util.Storage.Open(attachment.StorageKey)
+	if errors.Is(err, util.ErrObjectNotFound) {
+		return nil, nil, fmt.Errorf("读取附件失败:%w", models.ErrAttachmentNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取附件失败:%w", err)
+	}
+	return attachment, content, nil
+}
+
+// GetAttachmentUsage 获取用户的附件用量和配额
+func GetAttachmentUsage(userID uint) (*dto.AttachmentUsage, error) {
+	used, err := models.AttachmentUsageTx(db.DB, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("获取附件用量失败:%w", err)
+	}
+	return &dto.AttachmentUsage{
+		Used:        used,
+		Quota:       config.Cfg.Storage.UserQuota,
+		MaxFileSize: config.Cfg.Storage.MaxFileSize,
+	}, nil
+}
+
+// deleteStoredFiles 删除存储后端中的文件，失败时只记录日志，不影响已提交的数据库修改
+func deleteStoredFiles(keys []string) {
+	for _, key := range keys {
+		if err := util.Storage.Delete(key); err != nil {
+			logrus.Errorf("删除附件文件 %s 失败: %v", key, err)
+		}
+	}
+}
+
+func quotaError(used, quota int64) error {
+	return fmt.Errorf("%w:已使用 %d 字节，配额 %d 字节", ErrQuotaExceeded, used, quota)
+}
+
+// isAllowedType 判断识别出的类型是否在允许列表中，allowed 中以 / 结尾的项匹配该大类下的全部类型
+func isAllowedType(contentType string, allowed []string) bool {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for _, t := range allowed {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeFileName 去掉客户端文件名中的目录部分，并限制长度
+func normalizeFileName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
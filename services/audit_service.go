@@ -0,0 +1,401 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNothingToUndo 没有可撤销的修改
+	ErrNothingToUndo = errors.New("没有可撤销的修改")
+	// ErrUndoConflict 任务在该修改之后又被修改过，撤销会覆盖之后的修改
+	ErrUndoConflict = errors.New("任务在该修改之后已被修改，无法撤销")
+)
+
+// auditIgnoredFields 不计入差异的字段，这些字段不会被修改或每次写入都会变化
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"user_id":    true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	// 检查项进度由检查项计算得出
+	"checklist_progress": true,
+}
+
+// fieldChange 单个字段修改前后的取值
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// GetTaskHistory 获取任务的审计记录，回收站中的任务同样可以查看
+func GetTaskHistory(userID, id uint) ([]models.TaskAudit, error) {
+	audits, err := models.ListTaskAudits(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取修改记录失败:%w", err)
+	}
+	if len(audits) == 0 {
+		if _, err := models.GetTaskById(userID, id); err != nil {
+			if _, err := models.GetTrashedTask(userID, id); err != nil {
+				return nil, fmt.Errorf("获取任务失败:%w", err)
+			}
+		}
+	}
+	return audits, nil
+}
+
+// UndoTaskChange 撤销任务最近一次尚未撤销的修改，返回被撤销的记录
+func UndoTaskChange(userID, id uint, opts WriteOptions) (*models.TaskAudit, error) {
+	audit, err := models.GetLastUndoableAudit(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNothingToUndo
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取修改记录失败:%w", err)
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		return undoAudit(tx, audit, opts.Actor, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return audit, nil
+}
+
+// UndoLastAIBatch 撤销最近一次 AI 会话做出的全部修改，按相反顺序在同一事务中撤销
+// 任一修改无法撤销时整体回滚
+func UndoLastAIBatch(userID uint, opts WriteOptions) ([]models.TaskAudit, error) {
+	audits, err := models.ListLastAIBatch(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取修改记录失败:%w", err)
+	}
+	if len(audits) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	now := time.Now()
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range audits {
+			if err := undoAudit(tx, &audits[i], opts.Actor, now); err != nil {
+				return fmt.Errorf("撤销任务 %d 的修改失败:%w", audits[i].TaskID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+// undoAudit 在指定事务中撤销一条审计记录，并记录撤销本身
+func undoAudit(tx *gorm.DB, audit *models.TaskAudit, actor models.AuditActor, now time.Time) error {
+	var err error
+	switch audit.Action {
+	case models.AuditCreate, models.AuditRestore:
+		err = undoToTrash(tx, audit, actor)
+	case models.AuditUpdate:
+		err = undoUpdate(tx, audit, actor, now)
+	case models.AuditDelete:
+		err = undoDelete(tx, audit, actor, now)
+	default:
+		return ErrNothingToUndo
+	}
+	if err != nil {
+		return err
+	}
+	if err := models.MarkAuditUndone(tx, audit.ID, now); err != nil {
+		return ErrUndoConflict
+	}
+	audit.UndoneAt = &now
+	return nil
+}
+
+// undoToTrash 撤销创建或恢复：将任务及其子任务重新移入回收站
+func undoToTrash(tx *gorm.DB, audit *models.TaskAudit, actor models.AuditActor) error {
+	task, err := models.GetTaskByIdTx(tx, audit.UserID, audit.TaskID)
+	if err != nil {
+		return ErrUndoConflict
+	}
+	if err := checkUndoConflict(task, audit); err != nil {
+		return err
+	}
+	if err := trashTaskTx(tx, task); err != nil {
+		return err
+	}
+	return recordUndo(tx, actor, models.AuditDelete, task, nil, audit.ID)
+}
+
+// undoUpdate 撤销修改：将发生变化的字段恢复为修改前的取值
+func undoUpdate(tx *gorm.DB, audit *models.TaskAudit, actor models.AuditActor, now time.Time) error {
+	current, err := models.GetTaskByIdTx(tx, audit.UserID, audit.TaskID)
+	if err != nil {
+		return ErrUndoConflict
+	}
+	if err := checkUndoConflict(current, audit); err != nil {
+		return err
+	}
+
+	var changes map[string]fieldChange
+	if err := json.Unmarshal(audit.Changes, &changes); err != nil {
+		return fmt.Errorf("解析修改记录失败:%w", err)
+	}
+	_, fields, err := taskSnapshot(current)
+	if err != nil {
+		return err
+	}
+	for key, change := range changes {
+		fields[key] = change.From
+	}
+	// 快照中的标签只有名称、检查项只有内容和完成状态，单独恢复
+	var tags []string
+	if change, ok := changes["tags"]; ok {
+		if tags, err = snapshotTagNames(change.From); err != nil {
+			return err
+		}
+	}
+	var checklist []models.ChecklistItem
+	if change, ok := changes["checklist"]; ok {
+		if checklist, err = snapshotChecklist(change.From); err != nil {
+			return err
+		}
+	}
+	delete(fields, "tags")
+	delete(fields, "checklist")
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var reverted models.Task
+	if err := json.Unmarshal(data, &reverted); err != nil {
+		return err
+	}
+
+	// 撤销直接恢复修改前的状态，不受状态流转规则限制
+	if err := reverted.UpdateTx(tx); err != nil {
+		return err
+	}
+	reverted.Tags, reverted.Checklist = current.Tags, current.Checklist
+	if _, ok := changes["tags"]; ok {
+		if _, err := setTaskTagsTx(tx, &reverted, tags); err != nil {
+			return err
+		}
+	}
+	if _, ok := changes["checklist"]; ok {
+		if err := models.ReplaceChecklistItemsTx(tx, &reverted, checklist); err != nil {
+			return err
+		}
+		reverted.Checklist = checklist
+	}
+	if err := recordStatusChange(tx, &reverted, current.Status, now); err != nil {
+		return err
+	}
+	return recordUndo(tx, actor, models.AuditUpdate, current, &reverted, audit.ID)
+}
+
+// undoDelete 撤销删除：将任务移出回收站，并撤销删除时连带的修改（例如提升的子任务）
+func undoDelete(tx *gorm.DB, audit *models.TaskAudit, actor models.AuditActor, now time.Time) error {
+	task, err := models.GetTrashedTaskTx(tx, audit.UserID, audit.TaskID)
+	if err != nil {
+		return ErrUndoConflict
+	}
+	if err := models.RestoreTaskTx(tx, task); err != nil {
+		return err
+	}
+	restored, err := models.GetTaskByIdTx(tx, task.UserID, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordUndo(tx, actor, models.AuditRestore, task, restored, audit.ID); err != nil {
+		return err
+	}
+	cascaded, err := models.ListCascadedAuditsTx(tx, audit.ID)
+	if err != nil {
+		return fmt.Errorf("获取修改记录失败:%w", err)
+	}
+	for i := range cascaded {
+		if err := undoAudit(tx, &cascaded[i], actor, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUndoConflict 确认任务在被修改的字段上仍保持该记录修改后的取值
+func checkUndoConflict(current *models.Task, audit *models.TaskAudit) error {
+	var changes map[string]fieldChange
+	if err := json.Unmarshal(audit.Changes, &changes); err != nil {
+		return fmt.Errorf("解析修改记录失败:%w", err)
+	}
+	_, fields, err := taskSnapshot(current)
+	if err != nil {
+		return err
+	}
+	for key, change := range changes {
+		if !sameFieldValue(fields[key], change.To) {
+			return ErrUndoConflict
+		}
+	}
+	return nil
+}
+
+// recordAudit 在指定事务中记录一次任务修改，before 为空表示创建，after 为空表示删除
+// 修改没有改变任何字段时不记录
+func recordAudit(tx *gorm.DB, actor models.AuditActor, action string, before, after *models.Task) error {
+	audit, err := newAudit(actor, action, before, after)
+	if err != nil || audit == nil {
+		return err
+	}
+	return models.CreateAudit(tx, audit)
+}
+
+// recordUndo 记录对 undoOf 的撤销
+func recordUndo(tx *gorm.DB, actor models.AuditActor, action string, before, after *models.Task, undoOf uint) error {
+	audit, err := newAudit(actor, action, before, after)
+	if err != nil || audit == nil {
+		return err
+	}
+	audit.UndoOf = &undoOf
+	return models.CreateAudit(tx, audit)
+}
+
+// recordCascade 记录由 cascadeOf 连带产生的修改
+func recordCascade(tx *gorm.DB, actor models.AuditActor, before, after *models.Task, cascadeOf uint) error {
+	audit, err := newAudit(actor, models.AuditUpdate, before, after)
+	if err != nil || audit == nil {
+		return err
+	}
+	audit.CascadeOf = &cascadeOf
+	return models.CreateAudit(tx, audit)
+}
+
+func newAudit(actor models.AuditActor, action string, before, after *models.Task) (*models.TaskAudit, error) {
+	audit := &models.TaskAudit{
+		Action:    action,
+		ActorType: actor.Type,
+		ActorID:   actor.ID,
+	}
+
+	var beforeFields, afterFields map[string]interface{}
+	var err error
+	if before != nil {
+		audit.UserID, audit.TaskID = before.UserID, before.ID
+		if audit.Before, beforeFields, err = taskSnapshot(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		audit.UserID, audit.TaskID = after.UserID, after.ID
+		if audit.After, afterFields, err = taskSnapshot(after); err != nil {
+			return nil, err
+		}
+	}
+
+	if after != nil {
+		changes := diffFields(beforeFields, afterFields)
+		if len(changes) == 0 && action == models.AuditUpdate {
+			return nil, nil
+		}
+		if audit.Changes, err = json.Marshal(changes); err != nil {
+			return nil, err
+		}
+	}
+	return audit, nil
+}
+
+// taskSnapshot 将任务序列化为 JSON 快照，同时返回按字段展开的取值
+func taskSnapshot(task *models.Task) (json.RawMessage, map[string]interface{}, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	// 标签只比较名称，与标签的ID和颜色无关
+	names := tagNames(task.Tags)
+	sort.Strings(names)
+	tags := make([]interface{}, len(names))
+	for i, name := range names {
+		tags[i] = name
+	}
+	fields["tags"] = tags
+	// 检查项只比较内容和完成状态，重新创建的检查项ID不同也视为相同
+	checklist := make([]interface{}, len(task.Checklist))
+	for i, item := range task.Checklist {
+		checklist[i] = map[string]interface{}{"content": item.Content, "done": item.Done}
+	}
+	fields["checklist"] = checklist
+	return data, fields, nil
+}
+
+// diffFields 比较前后两份快照，返回发生变化的字段
+func diffFields(before, after map[string]interface{}) map[string]fieldChange {
+	changes := make(map[string]fieldChange)
+	for key, to := range after {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		from := before[key]
+		if !sameFieldValue(from, to) {
+			changes[key] = fieldChange{From: from, To: to}
+		}
+	}
+	return changes
+}
+
+// snapshotTagNames 解析快照中的标签名称列表
+func snapshotTagNames(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if value != nil && !ok {
+		return nil, fmt.Errorf("解析修改记录失败:tags格式错误")
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("解析修改记录失败:tags格式错误")
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// snapshotChecklist 解析快照中的检查项列表
+func snapshotChecklist(value interface{}) ([]models.ChecklistItem, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.ChecklistItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("解析修改记录失败:%w", err)
+	}
+	return items, nil
+}
+
+// sameFieldValue 比较快照中的两个取值，时间按秒比较以忽略数据库存储精度带来的差异
+func sameFieldValue(a, b interface{}) bool {
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		ta, errA := time.Parse(time.RFC3339Nano, sa)
+		tb, errB := time.Parse(time.RFC3339Nano, sb)
+		if errA == nil && errB == nil {
+			return ta.Truncate(time.Second).Equal(tb.Truncate(time.Second))
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultBoardColumnSize = 100
+	MaxBoardColumnSize     = 500
+)
+
+// GetBoard 按状态分列获取看板，每列按排序值排列，q.Status 不为空时只返回指定的列
+// q.Limit 为每列返回的最大任务数，分页和排序参数不适用于看板
+func GetBoard(userID uint, q dto.TaskQuery) ([]dto.BoardColumn, error) {
+	filter, err := buildTaskFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	statuses := models.BoardStatuses
+	if len(filter.Statuses) > 0 {
+		for _, status := range filter.Statuses {
+			if !models.IsValidTaskStatus(status) {
+				return nil, fmt.Errorf("%w:无效的状态 %s", ErrInvalidTaskQuery, status)
+			}
+		}
+		statuses = filter.Statuses
+	}
+	filter.Statuses = nil
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultBoardColumnSize
+	}
+	if limit > MaxBoardColumnSize {
+		limit = MaxBoardColumnSize
+	}
+
+	columns := make([]dto.BoardColumn, len(statuses))
+	for i, status := range statuses {
+		column := filter
+		column.Statuses = []string{status}
+		total, err := models.CountTasks(userID, column)
+		if err != nil {
+			return nil, fmt.Errorf("获取看板失败:%w", err)
+		}
+		tasks, err := models.ListBoardColumn(userID, filter, status, limit)
+		if err != nil {
+			return nil, fmt.Errorf("获取看板失败:%w", err)
+		}
+		columns[i] = dto.BoardColumn{Status: status, Tasks: tasks, Total: total}
+	}
+	return columns, nil
+}
+
+// MoveOnBoard 在看板中移动任务，状态和排序值在同一事务中修改，状态变化同样经过状态机校验并记录历史
+func MoveOnBoard(userID, id uint, req dto.BoardMoveRequest, opts WriteOptions) (*models.Task, error) {
+	var task *models.Task
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := getTaskForWrite(tx, userID, id, opts.Version)
+		if err != nil {
+			return err
+		}
+		status := req.Status
+		if status == "" {
+			status = current.Status
+		}
+		if !models.IsValidTaskStatus(status) {
+			return fmt.Errorf("%w:无效的状态 %s", ErrInvalidTask, status)
+		}
+
+		rank, err := boardRankTx(tx, userID, current.ID, status, req)
+		if errors.Is(err, models.ErrRankConflict) {
+			// 相邻任务的排序值冲突或已经过长，重新分配该列的排序值后再计算一次
+			if err = models.RebalanceRanksTx(tx, userID, status); err != nil {
+				return fmt.Errorf("移动任务失败:%w", err)
+			}
+			rank, err = boardRankTx(tx, userID, current.ID, status, req)
+		}
+		if err != nil {
+			return err
+		}
+
+		patch := dto.TaskPatch{Rank: &rank}
+		if status != current.Status {
+			patch.Status = &status
+		}
+		task, err = patchTaskTx(tx, userID, id, patch, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// boardRankTx 计算任务移动到 status 列中指定位置后的排序值
+func boardRankTx(tx *gorm.DB, userID, id uint, status string, req dto.BoardMoveRequest) (string, error) {
+	prev, next := "", ""
+	var err error
+	switch {
+	case req.AfterID != nil || req.BeforeID != nil:
+		if req.AfterID != nil {
+			if prev, err = neighborRankTx(tx, userID, id, status, *req.AfterID); err != nil {
+				return "", err
+			}
+		}
+		if req.BeforeID != nil {
+			if next, err = neighborRankTx(tx, userID, id, status, *req.BeforeID); err != nil {
+				return "", err
+			}
+		}
+		// 只给出一侧的任务时，另一侧取列中实际相邻的任务
+		if req.BeforeID == nil {
+			next, err = models.NeighborRankTx(tx, userID, status, prev, true, id)
+		} else if req.AfterID == nil {
+			prev, err = models.NeighborRankTx(tx, userID, status, next, false, id)
+		}
+		if err != nil {
+			return "", fmt.Errorf("移动任务失败:%w", err)
+		}
+	default:
+		if prev, err = models.LastRankTx(tx, userID, status); err != nil {
+			return "", fmt.Errorf("移动任务失败:%w", err)
+		}
+	}
+
+	rank, err := models.RankBetween(prev, next)
+	if err != nil {
+		return "", err
+	}
+	if len(rank) > models.MaxRankLength {
+		return "", models.ErrRankConflict
+	}
+	return rank, nil
+}
+
+// neighborRankTx 获取移动目标位置旁边的任务的排序值，该任务必须位于目标列中
+func neighborRankTx(tx *gorm.DB, userID, id uint, status string, neighborID uint) (string, error) {
+	if neighborID == id {
+		return "", fmt.Errorf("%w:不能相对任务自身移动", ErrInvalidTask)
+	}
+	neighbor, err := models.GetTaskByIdTx(tx, userID, neighborID)
+	if err != nil {
+		return "", fmt.Errorf("获取相邻任务失败:%w", err)
+	}
+	if neighbor.Status != status {
+		return "", fmt.Errorf("%w:相邻任务 %d 不在 %s 列中", ErrInvalidTask, neighborID, status)
+	}
+	if neighbor.Rank == "" {
+		return "", models.ErrRankConflict
+	}
+	return neighbor.Rank, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 批量操作的执行方式
+const (
+	BulkAtomic     = "atomic"      // 任一操作失败则全部回滚
+	BulkBestEffort = "best_effort" // 失败的操作单独回滚，其余照常提交
+)
+
+// 批量操作类型
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+	BulkStatus = "status"
+)
+
+// MaxBulkOperations 单次批量操作的最大数量
+const MaxBulkOperations = 100
+
+// ErrBulkRolledBack 操作本身成功，但因同批次其他操作失败而被回滚
+var ErrBulkRolledBack = errors.New("同批次的其他操作失败，已回滚")
+
+// BulkOutcome 单个操作的执行结果
+type BulkOutcome struct {
+	Index int
+	Op    string
+	ID    uint
+	Task  *models.Task
+	Err   error
+}
+
+// BulkApply 在同一个数据库事务中依次执行批量操作，返回每个操作的结果以及是否有修改被提交
+// best_effort 模式下每个操作在各自的保存点中执行，失败只回滚该操作
+func BulkApply(userID uint, mode string, ops []dto.BulkOperation, opts WriteOptions) ([]BulkOutcome, bool, error) {
+	if mode == "" {
+		mode = BulkAtomic
+	}
+	if mode != BulkAtomic && mode != BulkBestEffort {
+		return nil, false, fmt.Errorf("%w:不支持的批量模式 %s", ErrInvalidTaskQuery, mode)
+	}
+	if len(ops) > MaxBulkOperations {
+		return nil, false, fmt.Errorf("%w:单次最多 %d 个操作", ErrInvalidTaskQuery, MaxBulkOperations)
+	}
+
+	outcomes := make([]BulkOutcome, len(ops))
+	for i, op := range ops {
+		outcomes[i] = BulkOutcome{Index: i, Op: op.Op, ID: op.ID}
+	}
+	failed := 0
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range ops {
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				task, err := applyBulkOperation(sp, userID, op, opts)
+				outcomes[i].Task = task
+				return err
+			})
+			if err == nil {
+				if outcomes[i].Task != nil {
+					outcomes[i].ID = outcomes[i].Task.ID
+				}
+				continue
+			}
+			outcomes[i].Err, outcomes[i].Task = err, nil
+			failed++
+			if mode == BulkAtomic {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		for i := range outcomes {
+			if outcomes[i].Err == nil {
+				outcomes[i].Err, outcomes[i].Task = ErrBulkRolledBack, nil
+			}
+		}
+		return outcomes, false, nil
+	}
+	return outcomes, failed < len(ops), nil
+}
+
+// applyBulkOperation 在指定事务中执行单个操作
+func applyBulkOperation(tx *gorm.DB, userID uint, op dto.BulkOperation, opts WriteOptions) (*models.Task, error) {
+	opts.Version, opts.Force = op.Version, op.Force
+	switch op.Op {
+	case BulkCreate:
+		if op.Task == nil {
+			return nil, fmt.Errorf("%w:create 操作缺少 task", ErrInvalidTask)
+		}
+		task := *op.Task
+		task.UserID = userID
+		task.SeriesID, task.RecurrenceID = nil, nil
+		if op.ProjectName != "" {
+			project, err := resolveProjectTx(tx, userID, op.ProjectName)
+			if err != nil {
+				return nil, err
+			}
+			task.ProjectID = &project.ID
+		}
+		if err := createTaskTx(tx, &task, opts); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	case BulkUpdate:
+		if op.Patch == nil {
+			return nil, fmt.Errorf("%w:update 操作缺少 patch", ErrInvalidTask)
+		}
+		return patchTaskTx(tx, userID, op.ID, *op.Patch, opts)
+	case BulkStatus:
+		status := op.Status
+		return patchTaskTx(tx, userID, op.ID, dto.TaskPatch{Status: &status}, opts)
+	case BulkDelete:
+		return nil, deleteTaskTx(tx, userID, op.ID, op.Children, opts)
+	default:
+		return nil, fmt.Errorf("%w:不支持的操作 %s", ErrInvalidTask, op.Op)
+	}
+}
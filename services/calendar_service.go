@@ -0,0 +1,250 @@
+package services
+
+import (
+	"AITodo/dto"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInvalidCalendarView 不支持的日历视图
+var ErrInvalidCalendarView = errors.New("无效的日历视图")
+
+// minCalendarSlot 定时区域中任务至少占用的时间段，用于排布没有时长或时长很短的任务
+const minCalendarSlot = 15 * time.Minute
+
+// calendarSpan 一次发生在日历窗口中覆盖的天数范围
+type calendarSpan struct {
+	occurrence dto.TaskOccurrence
+	start, end time.Time // 统一到日历所用的时区，没有开始时间的任务视为截止时刻的一个时间点
+	allDay     bool
+	first      int // 覆盖的第一天在窗口中的下标
+	last       int // 覆盖的最后一天在窗口中的下标
+	row        int // 全天区域中的行号
+}
+
+// GetCalendar 按视图返回 date 所在日、周（周一开始）或月的任务排布，重复任务按发生展开
+// 全天任务和跨天任务放在每天的全天区域并分配跨天一致的行号，其余任务按时间放在定时区域并分配并列的列
+// 日期的划分和全天任务的判断均以 loc 时区为准
+func GetCalendar(userID uint, view string, date time.Time, loc *time.Location) (*dto.CalendarResponse, error) {
+	start, end, err := calendarWindow(view, date, loc)
+	if err != nil {
+		return nil, err
+	}
+	if view == "" {
+		view = dto.CalendarViewWeek
+	}
+	occurrences, err := ExpandOccurrences(userID, start, end.Add(-time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	var dayStarts []time.Time
+	result := &dto.CalendarResponse{View: view, Start: start, End: end}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		dayStarts = append(dayStarts, day)
+		result.Days = append(result.Days, dto.CalendarDay{
+			Date:   day.Format("2006-01-02"),
+			AllDay: []dto.CalendarEntry{},
+			Timed:  []dto.CalendarEntry{},
+		})
+	}
+
+	var spans []calendarSpan
+	for _, occ := range occurrences {
+		span, ok := newCalendarSpan(occ, dayStarts, end, loc)
+		if !ok {
+			continue
+		}
+		// 跨天的任务即使只有一部分落在窗口内也放在全天区域
+		if span.allDay || !sameDay(span.start, lastInstant(span.start, span.end)) {
+			spans = append(spans, span)
+			continue
+		}
+		day := &result.Days[span.first]
+		day.Timed = append(day.Timed, calendarEntry(span, dayStarts[span.first], dayEnd(dayStarts, span.first, end)))
+	}
+
+	result.AllDayRows = assignCalendarRows(spans)
+	for _, span := range spans {
+		for i := span.first; i <= span.last; i++ {
+			day := &result.Days[i]
+			day.AllDay = append(day.AllDay, calendarEntry(span, dayStarts[i], dayEnd(dayStarts, i, end)))
+		}
+	}
+	for i := range result.Days {
+		day := &result.Days[i]
+		sort.SliceStable(day.AllDay, func(a, b int) bool { return day.AllDay[a].Row < day.AllDay[b].Row })
+		assignCalendarLanes(day.Timed)
+	}
+	return result, nil
+}
+
+// calendarWindow 计算视图对应的日期窗口 [start, end)，以 loc 时区划分日期
+func calendarWindow(view string, date time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	date = date.In(loc)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	switch view {
+	case dto.CalendarViewDay:
+		return day, day.AddDate(0, 0, 1), nil
+	case dto.CalendarViewWeek, "":
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return monday, monday.AddDate(0, 0, 7), nil
+	case dto.CalendarViewMonth:
+		first := day.AddDate(0, 0, 1-day.Day())
+		return first, first.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w:%s，可选值为 day/week/month", ErrInvalidCalendarView, view)
+}
+
+// newCalendarSpan 计算一次发生覆盖窗口中的哪些天，与窗口没有交集时返回 false
+func newCalendarSpan(occ dto.TaskOccurrence, dayStarts []time.Time, end time.Time, loc *time.Location) (calendarSpan, bool) {
+	span := calendarSpan{occurrence: occ, start: occ.StartDate.In(loc), end: occ.DueDate.In(loc), first: -1}
+	if occ.StartDate.IsZero() {
+		span.start = span.end
+	}
+	if span.end.Before(span.start) {
+		span.end = span.start
+	}
+	span.allDay = isAllDay(span.start, span.end)
+
+	for i, dayStart := range dayStarts {
+		// 结束于零点的任务不占用当天，没有时长的任务只出现在所在的那一天
+		overlaps := span.start.Before(dayEnd(dayStarts, i, end)) &&
+			(span.end.After(dayStart) || !span.start.Before(dayStart))
+		if !overlaps {
+			continue
+		}
+		if span.first < 0 {
+			span.first = i
+		}
+		span.last = i
+	}
+	return span, span.first >= 0
+}
+
+// calendarEntry 生成一次发生在某一天中的部分
+func calendarEntry(span calendarSpan, dayStart, dayEnd time.Time) dto.CalendarEntry {
+	segmentStart, segmentEnd := span.start, span.end
+	if segmentStart.Before(dayStart) {
+		segmentStart = dayStart
+	}
+	if segmentEnd.After(dayEnd) {
+		segmentEnd = dayEnd
+	}
+	return dto.CalendarEntry{
+		TaskOccurrence:  span.occurrence,
+		AllDay:          span.allDay,
+		SegmentStart:    segmentStart,
+		SegmentEnd:      segmentEnd,
+		StartMinute:     int(segmentStart.Sub(dayStart) / time.Minute),
+		EndMinute:       int(segmentEnd.Sub(dayStart) / time.Minute),
+		ContinuesBefore: span.start.Before(dayStart),
+		ContinuesAfter:  span.end.After(dayEnd),
+		Row:             span.row,
+	}
+}
+
+// assignCalendarRows 为全天区域的任务分配行号，先开始、跨度更长的任务排在上面，返回使用的行数
+func assignCalendarRows(spans []calendarSpan) int {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].first != spans[j].first {
+			return spans[i].first < spans[j].first
+		}
+		if spans[i].last != spans[j].last {
+			return spans[i].last > spans[j].last
+		}
+		return spans[i].start.Before(spans[j].start)
+	})
+	var rowEnds []int // 每一行最后被占用的日期下标
+	for i := range spans {
+		row := 0
+		for row < len(rowEnds) && rowEnds[row] >= spans[i].first {
+			row++
+		}
+		if row == len(rowEnds) {
+			rowEnds = append(rowEnds, spans[i].last)
+		} else {
+			rowEnds[row] = spans[i].last
+		}
+		spans[i].row = row
+	}
+	return len(rowEnds)
+}
+
+// assignCalendarLanes 为同一天的定时任务分配并列显示的列
+// 时间上相互重叠（直接或间接）的任务组成一组，组内按开始时间依次放入最左边的空闲列，组内任务共享列数
+func assignCalendarLanes(entries []dto.CalendarEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].SegmentStart.Equal(entries[j].SegmentStart) {
+			return entries[i].SegmentStart.Before(entries[j].SegmentStart)
+		}
+		return entries[i].SegmentEnd.After(entries[j].SegmentEnd)
+	})
+
+	var laneEnds []time.Time // 当前组内每一列最后结束的时间
+	var groupEnd time.Time
+	groupStart := 0
+	closeGroup := func(end int) {
+		for k := groupStart; k < end; k++ {
+			entries[k].Lanes = len(laneEnds)
+		}
+	}
+	for i := range entries {
+		start, end := entries[i].SegmentStart, calendarLaneEnd(entries[i])
+		if i > groupStart && !start.Before(groupEnd) {
+			closeGroup(i)
+			laneEnds, groupStart = nil, i
+		}
+		lane := 0
+		for lane < len(laneEnds) && start.Before(laneEnds[lane]) {
+			lane++
+		}
+		if lane == len(laneEnds) {
+			laneEnds = append(laneEnds, end)
+		} else {
+			laneEnds[lane] = end
+		}
+		entries[i].Lane = lane
+		if i == groupStart || end.After(groupEnd) {
+			groupEnd = end
+		}
+	}
+	closeGroup(len(entries))
+}
+
+// calendarLaneEnd 计算排布时占用的结束时间，没有时长的任务按至少占用一个最小时间段处理
+func calendarLaneEnd(entry dto.CalendarEntry) time.Time {
+	if end := entry.SegmentStart.Add(minCalendarSlot); entry.SegmentEnd.Before(end) {
+		return end
+	}
+	return entry.SegmentEnd
+}
+
+// isAllDay 判断任务是否为全天任务：在 start 所在的时区从某天 00:00 开始，到某天 23:59 结束
+func isAllDay(start, end time.Time) bool {
+	return start.Hour() == 0 && start.Minute() == 0 && start.Second() == 0 &&
+		end.Hour() == 23 && end.Minute() == 59 && end.After(start)
+}
+
+// lastInstant 返回任务实际占用的最后时刻，结束于零点的任务不占用结束当天
+func lastInstant(start, end time.Time) time.Time {
+	if end.After(start) {
+		return end.Add(-time.Nanosecond)
+	}
+	return end
+}
+
+// sameDay 判断两个时间是否为同一天
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// dayEnd 返回窗口中第 i 天的结束时间（不含）
+func dayEnd(dayStarts []time.Time, i int, end time.Time) time.Time {
+	if i+1 < len(dayStarts) {
+		return dayStarts[i+1]
+	}
+	return end
+}
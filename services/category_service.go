@@ -0,0 +1,142 @@
+package services
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCategory 类别字段不合法
+var ErrInvalidCategory = errors.New("类别数据不合法")
+
+// ErrCategoryExists 同名类别已存在
+var ErrCategoryExists = errors.New("类别已存在")
+
+// ListCategories 获取当前用户的类别
+func ListCategories(userID uint) ([]models.Category, error) {
+	categories, err := models.ListCategories(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取类别失败:%w", err)
+	}
+	return categories, nil
+}
+
+// CategoryNames 获取当前用户全部类别的名称，用于生成 AI 工具的可选值
+func CategoryNames(userID uint) ([]string, error) {
+	categories, err := ListCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.Name
+	}
+	return names, nil
+}
+
+// CreateCategory 创建类别
+func CreateCategory(userID uint, req dto.CreateCategoryRequest) (*models.Category, error) {
+	category := &models.Category{UserID: userID, Name: strings.TrimSpace(req.Name), Color: req.Color}
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+	if err := checkCategoryName(userID, 0, category.Name); err != nil {
+		return nil, err
+	}
+	if err := models.CreateCategory(category); err != nil {
+		return nil, fmt.Errorf("创建类别失败:%w", err)
+	}
+	return category, nil
+}
+
+// UpdateCategory 修改类别名称或颜色，重命名时任务随之改名
+func UpdateCategory(userID, id uint, patch dto.CategoryPatch) (*models.Category, error) {
+	category, err := models.GetCategoryById(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取类别失败:%w", err)
+	}
+	oldName := category.Name
+
+	if patch.Name != nil {
+		category.Name = strings.TrimSpace(*patch.Name)
+	}
+	if patch.Color != nil {
+		category.Color = *patch.Color
+	}
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+	if category.Name != oldName {
+		if oldName == models.DefaultCategoryName {
+			return nil, fmt.Errorf("%w:默认类别不能重命名", ErrInvalidCategory)
+		}
+		if err := checkCategoryName(userID, id, category.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := models.UpdateCategory(category, oldName); err != nil {
+		return nil, fmt.Errorf("更新类别失败:%w", err)
+	}
+	return category, nil
+}
+
+// DeleteCategory 删除类别，其中的任务归入默认类别
+func DeleteCategory(userID, id uint) error {
+	category, err := models.GetCategoryById(userID, id)
+	if err != nil {
+		return fmt.Errorf("获取类别失败:%w", err)
+	}
+	if category.Name == models.DefaultCategoryName {
+		return fmt.Errorf("%w:默认类别不能删除", ErrInvalidCategory)
+	}
+	if err := models.DeleteCategory(category); err != nil {
+		return fmt.Errorf("删除类别失败:%w", err)
+	}
+	return nil
+}
+
+// validateTaskCategory 校验任务的类别属于当前用户
+func validateTaskCategory(tx *gorm.DB, userID uint, name string) error {
+	categories, err := models.ListCategoriesTx(tx, userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if c.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w:类别 %s 不存在", ErrInvalidTask, name)
+}
+
+// checkCategoryName 检查名称是否与用户的其他类别重复
+func checkCategoryName(userID, id uint, name string) error {
+	categories, err := models.ListCategories(userID)
+	if err != nil {
+		return fmt.Errorf("获取类别失败:%w", err)
+	}
+	for _, c := range categories {
+		if c.Name == name && c.ID != id {
+			return ErrCategoryExists
+		}
+	}
+	return nil
+}
+
+func validateCategory(category *models.Category) error {
+	if category.Name == "" {
+		return fmt.Errorf("%w:name不能为空", ErrInvalidCategory)
+	}
+	if len(category.Name) > 100 {
+		return fmt.Errorf("%w:name长度不能超过100", ErrInvalidCategory)
+	}
+	if category.Color != "" && !colorPattern.MatchString(category.Color) {
+		return fmt.Errorf("%w:color格式应为#RRGGBB", ErrInvalidCategory)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetChecklist 获取任务的检查项及完成进度
+func GetChecklist(userID, taskID uint) (*dto.TaskChecklist, error) {
+	task, err := models.GetTaskById(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	items, err := models.ListChecklistItems(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取检查项失败:%w", err)
+	}
+	return newTaskChecklist(items, task.Version), nil
+}
+
+// AddChecklistItem 在任务末尾添加检查项，返回修改后的检查项列表
+func AddChecklistItem(userID, taskID uint, content string, opts WriteOptions) (*dto.TaskChecklist, error) {
+	return updateChecklist(userID, taskID, opts, func(tx *gorm.DB, task *models.Task) error {
+		count, err := models.CountChecklistItems(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		if count >= models.MaxChecklistItems {
+			return fmt.Errorf("%w:单个任务最多 %d 个检查项", ErrInvalidTask, models.MaxChecklistItems)
+		}
+		content, err := normalizeChecklistContent(content)
+		if err != nil {
+			return err
+		}
+		return models.CreateChecklistItemsTx(tx, task, []models.ChecklistItem{{Content: content}})
+	})
+}
+
+// UpdateChecklistItem 修改检查项内容或完成状态
+func UpdateChecklistItem(userID, taskID, itemID uint, patch dto.ChecklistItemPatch, opts WriteOptions) (*dto.TaskChecklist, error) {
+	return updateChecklist(userID, taskID, opts, func(tx *gorm.DB, task *models.Task) error {
+		item, err := models.GetChecklistItemTx(tx, task.ID, itemID)
+		if err != nil {
+			return err
+		}
+		var columns []string
+		if patch.Content != nil {
+			if item.Content, err = normalizeChecklistContent(*patch.Content); err != nil {
+				return err
+			}
+			columns = append(columns, "content")
+		}
+		if patch.Done != nil {
+			item.Done = *patch.Done
+			columns = append(columns, "done")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		return item.UpdateColumnsTx(tx, columns...)
+	})
+}
+
+// ReorderChecklist 按 ids 的顺序重新排列检查项
+func ReorderChecklist(userID, taskID uint, ids []uint, opts WriteOptions) (*dto.TaskChecklist, error) {
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return nil, fmt.Errorf("%w:检查项ID重复", ErrInvalidTask)
+		}
+		seen[id] = true
+	}
+	return updateChecklist(userID, taskID, opts, func(tx *gorm.DB, task *models.Task) error {
+		err := models.ReorderChecklistItemsTx(tx, task.ID, ids)
+		if errors.Is(err, models.ErrChecklistItemNotFound) {
+			return fmt.Errorf("%w:ids 必须包含任务的全部检查项", err)
+		}
+		return err
+	})
+}
+
+// DeleteChecklistItem 删除检查项
+func DeleteChecklistItem(userID, taskID, itemID uint, opts WriteOptions) (*dto.TaskChecklist, error) {
+	return updateChecklist(userID, taskID, opts, func(tx *gorm.DB, task *models.Task) error {
+		return models.DeleteChecklistItemTx(tx, task.ID, itemID)
+	})
+}
+
+// updateChecklist 在事务中校验任务归属和版本号并执行修改，返回修改后的检查项列表
+// 检查项属于任务内容，修改后任务版本号加一并记录审计日志，以便撤销
+func updateChecklist(userID, taskID uint, opts WriteOptions, update func(tx *gorm.DB, task *models.Task) error) (*dto.TaskChecklist, error) {
+	var task *models.Task
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if task, err = getTaskForWrite(tx, userID, taskID, opts.Version); err != nil {
+			return err
+		}
+		before := *task
+		if err := update(tx, task); err != nil {
+			return err
+		}
+		if task.Checklist, err = models.ListChecklistItemsTx(tx, taskID); err != nil {
+			return err
+		}
+		return saveTaskTx(tx, task, &before, []string{}, opts.Actor, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newTaskChecklist(task.Checklist, task.Version), nil
+}
+
+// normalizeChecklist 校验创建任务时附带的检查项，忽略内容为空的检查项
+func normalizeChecklist(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+	if len(items) > models.MaxChecklistItems {
+		return nil, fmt.Errorf("%w:单个任务最多 %d 个检查项", ErrInvalidTask, models.MaxChecklistItems)
+	}
+	result := make([]models.ChecklistItem, 0, len(items))
+	for _, item := range items {
+		if strings.TrimSpace(item.Content) == "" {
+			continue
+		}
+		content, err := normalizeChecklistContent(item.Content)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, models.ChecklistItem{Content: content, Done: item.Done})
+	}
+	return result, nil
+}
+
+func normalizeChecklistContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("%w:检查项内容不能为空", ErrInvalidTask)
+	}
+	if len(content) > 255 {
+		return "", fmt.Errorf("%w:检查项内容长度不能超过255", ErrInvalidTask)
+	}
+	return content, nil
+}
+
+func newTaskChecklist(items []models.ChecklistItem, version uint) *dto.TaskChecklist {
+	if items == nil {
+		items = []models.ChecklistItem{}
+	}
+	return &dto.TaskChecklist{Items: items, Progress: models.NewChecklistProgress(items), Version: version}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidComment 评论内容不合法
+var ErrInvalidComment = errors.New("评论内容不合法")
+
+// ListComments 按时间顺序获取任务的评论
+func ListComments(userID, taskID uint) ([]models.TaskComment, error) {
+	if _, err := models.GetTaskById(userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	comments, err := models.ListComments(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取评论失败:%w", err)
+	}
+	return comments, nil
+}
+
+// CreateComment 为任务添加评论
+func CreateComment(userID, taskID uint, body string) (*models.TaskComment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	var comment *models.TaskComment
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		created := models.TaskComment{TaskID: taskID, UserID: userID, Body: body}
+		if err := models.CreateCommentTx(tx, &created); err != nil {
+			return fmt.Errorf("创建评论失败:%w", err)
+		}
+		comment, err = models.GetCommentTx(tx, taskID, created.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// UpdateComment 修改评论内容，只有评论作者可以修改
+func UpdateComment(userID, taskID, id uint, body string) (*models.TaskComment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	var comment *models.TaskComment
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if comment, err = getOwnCommentTx(tx, userID, taskID, id); err != nil {
+			return err
+		}
+		comment.Body = body
+		if err := comment.UpdateBodyTx(tx); err != nil {
+			return fmt.Errorf("修改评论失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment 删除评论，只有评论作者可以删除
+func DeleteComment(userID, taskID, id uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := getOwnCommentTx(tx, userID, taskID, id); err != nil {
+			return err
+		}
+		if err := models.DeleteCommentTx(tx, taskID, id); err != nil {
+			return fmt.Errorf("删除评论失败:%w", err)
+		}
+		return nil
+	})
+}
+
+// GetTaskActivity 获取任务的动态，将评论与状态变化按时间顺序合并
+func GetTaskActivity(userID, taskID uint) ([]dto.TaskActivity, error) {
+	comments, err := ListComments(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := models.ListStatusHistory(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取状态记录失败:%w", err)
+	}
+
+	activities := make([]dto.TaskActivity, 0, len(comments)+len(histories))
+	for i := range histories {
+		activities = append(activities, dto.TaskActivity{
+			Type:         dto.ActivityStatusChange,
+			Time:         histories[i].ChangedAt,
+			StatusChange: &histories[i],
+		})
+	}
+	for i := range comments {
+		activities = append(activities, dto.TaskActivity{
+			Type:    dto.ActivityComment,
+			Time:    comments[i].CreatedAt,
+			Comment: &comments[i],
+		})
+	}
+	// 两类记录各自已按时间排序，稳定排序保证同一时刻的状态变化排在评论之前
+	sort.SliceStable(activities, func(i, j int) bool {
+		return activities[i].Time.Before(activities[j].Time)
+	})
+	return activities, nil
+}
+
+// getOwnCommentTx 获取当前用户在任务下发表的评论
+func getOwnCommentTx(tx *gorm.DB, userID, taskID, id uint) (*models.TaskComment, error) {
+	if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	comment, err := models.GetCommentTx(tx, taskID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取评论失败:%w", err)
+	}
+	if comment.UserID != userID {
+		return nil, fmt.Errorf("获取评论失败:%w", models.ErrCommentNotFound)
+	}
+	return comment, nil
+}
+
+func normalizeCommentBody(body string) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("%w:评论内容不能为空", ErrInvalidComment)
+	}
+	if len(body) > models.MaxCommentLength {
+		return "", fmt.Errorf("%w:评论内容长度不能超过%d", ErrInvalidComment, models.MaxCommentLength)
+	}
+	return body, nil
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrFocusSessionActive 用户已有进行中的专注会话
+	ErrFocusSessionActive = errors.New("已有进行中的专注会话")
+	// ErrNoActiveFocusSession 用户没有进行中的专注会话
+	ErrNoActiveFocusSession = errors.New("没有进行中的专注会话")
+	// ErrInvalidFocusAction 当前阶段不允许该操作
+	ErrInvalidFocusAction = errors.New("当前阶段不允许该操作")
+	// ErrInvalidFocusSettings 番茄钟时长设置不合法
+	ErrInvalidFocusSettings = errors.New("番茄钟设置不合法")
+)
+
+// 番茄钟的默认设置
+const (
+	defaultWorkMinutes      = 25
+	defaultBreakMinutes     = 5
+	defaultLongBreakMinutes = 15
+	defaultLongBreakEvery   = 4
+)
+
+// StartFocusSession 为任务开始番茄钟会话，立即进入第一个专注阶段
+// 每个用户同一时间只能有一个进行中的会话，换设备时应获取当前会话而不是重新开始
+func StartFocusSession(userID, taskID uint, req dto.FocusStartRequest) (*models.FocusSession, error) {
+	session, err := newFocusSession(req)
+	if err != nil {
+		return nil, err
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		// 锁定用户记录后再检查，并发的开始请求只有一个能通过
+		active, err := models.GetActiveFocusSessionTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取专注会话失败:%w", err)
+		}
+		if active != nil {
+			return fmt.Errorf("%w:任务 %d 正在专注", ErrFocusSessionActive, active.TaskID)
+		}
+
+		now := time.Now()
+		session.TaskID = taskID
+		session.UserID = userID
+		session.Phase = models.FocusPhaseWork
+		session.PhaseStartedAt = now
+		session.StartedAt = now
+		if err := models.CreateFocusSessionTx(tx, session); err != nil {
+			return fmt.Errorf("开始专注失败:%w", err)
+		}
+		session.RemainingSeconds = focusRemaining(session, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetCurrentFocusSession 获取用户进行中的专注会话，没有时返回 nil
+// 已到时的阶段在读取时推进，因此任何设备读取到的都是当前的状态
+func GetCurrentFocusSession(userID uint) (*models.FocusSession, error) {
+	var session *models.FocusSession
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		session, err = models.GetActiveFocusSessionTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取专注会话失败:%w", err)
+		}
+		if session == nil {
+			return nil
+		}
+		if err := advanceFocusSession(tx, session, time.Now()); err != nil {
+			return err
+		}
+		if err := session.SaveTx(tx); err != nil {
+			return fmt.Errorf("保存专注会话失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListFocusSessions 获取任务的专注会话
+func ListFocusSessions(userID, taskID uint) ([]models.FocusSession, error) {
+	if _, err := models.GetTaskById(userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	sessions, err := models.ListFocusSessions(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取专注会话失败:%w", err)
+	}
+	now := time.Now()
+	for i := range sessions {
+		sessions[i].RemainingSeconds = focusRemaining(&sessions[i], now)
+	}
+	return sessions, nil
+}
+
+// PauseFocusSession 暂停当前阶段，暂停期间不计入专注时长
+func PauseFocusSession(userID uint) (*models.FocusSession, error) {
+	return updateFocusSession(userID, func(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+		if s.PausedAt != nil || s.PhaseLength() == 0 {
+			return fmt.Errorf("%w:只能暂停正在进行的专注或休息", ErrInvalidFocusAction)
+		}
+		if s.Phase == models.FocusPhaseWork {
+			if err := recordFocusSegment(tx, s, now, false); err != nil {
+				return err
+			}
+		}
+		s.PhaseElapsed += int64(now.Sub(s.PhaseStartedAt) / time.Second)
+		s.PausedAt = &now
+		return nil
+	})
+}
+
+// ResumeFocusSession 从暂停处继续当前阶段
+func ResumeFocusSession(userID uint) (*models.FocusSession, error) {
+	return updateFocusSession(userID, func(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+		if s.PausedAt == nil {
+			return fmt.Errorf("%w:会话没有暂停", ErrInvalidFocusAction)
+		}
+		s.PhaseStartedAt = now
+		s.PausedAt = nil
+		return nil
+	})
+}
+
+// InterruptFocusSession 记录一次专注阶段中的打断，不影响计时
+func InterruptFocusSession(userID uint) (*models.FocusSession, error) {
+	return updateFocusSession(userID, func(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+		if s.Phase != models.FocusPhaseWork {
+			return fmt.Errorf("%w:只能在专注阶段记录打断", ErrInvalidFocusAction)
+		}
+		s.Interruptions++
+		return nil
+	})
+}
+
+// NextFocusPomodoro 跳过剩余的休息或在休息结束后开始下一个番茄钟
+func NextFocusPomodoro(userID uint) (*models.FocusSession, error) {
+	return updateFocusSession(userID, func(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+		if s.Phase == models.FocusPhaseWork {
+			return fmt.Errorf("%w:专注阶段不能跳过，请结束会话", ErrInvalidFocusAction)
+		}
+		s.Phase = models.FocusPhaseWork
+		s.PhaseStartedAt = now
+		s.PhaseElapsed = 0
+		s.PausedAt = nil
+		return nil
+	})
+}
+
+// StopFocusSession 结束会话，未完成的番茄钟只计入专注时长，不计入完成数量
+func StopFocusSession(userID uint) (*models.FocusSession, error) {
+	return updateFocusSession(userID, func(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+		if s.Phase == models.FocusPhaseWork && s.PausedAt == nil {
+			if err := recordFocusSegment(tx, s, now, false); err != nil {
+				return err
+			}
+		}
+		s.Phase = models.FocusPhaseFinished
+		s.PhaseStartedAt = now
+		s.PhaseElapsed = 0
+		s.PausedAt = nil
+		s.EndedAt = &now
+		return nil
+	})
+}
+
+// updateFocusSession 锁定并推进用户进行中的会话，执行 action 后保存
+func updateFocusSession(userID uint, action func(tx *gorm.DB, s *models.FocusSession, now time.Time) error) (*models.FocusSession, error) {
+	var session *models.FocusSession
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		session, err = models.GetActiveFocusSessionTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取专注会话失败:%w", err)
+		}
+		if session == nil {
+			return ErrNoActiveFocusSession
+		}
+		now := time.Now()
+		if err := advanceFocusSession(tx, session, now); err != nil {
+			return err
+		}
+		if err := action(tx, session, now); err != nil {
+			return err
+		}
+		session.RemainingSeconds = focusRemaining(session, now)
+		if err := session.SaveTx(tx); err != nil {
+			return fmt.Errorf("保存专注会话失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// advanceFocusSession 将已到时的阶段推进到 now 所处的阶段
+// 专注结束后自动进入休息，休息结束后停在 idle 等待用户开始下一个番茄钟，
+// 因此无人操作的会话最多只会多记一个番茄钟
+func advanceFocusSession(tx *gorm.DB, s *models.FocusSession, now time.Time) error {
+	for s.PausedAt == nil && s.PhaseLength() > 0 {
+		end := s.PhaseStartedAt.Add(s.PhaseLength() - time.Duration(s.PhaseElapsed)*time.Second)
+		if now.Before(end) {
+			break
+		}
+		if s.Phase == models.FocusPhaseWork {
+			if err := recordFocusSegment(tx, s, end, true); err != nil {
+				return err
+			}
+			s.CompletedPomodoros++
+			s.Phase = models.FocusPhaseShortBreak
+			if s.CompletedPomodoros%s.LongBreakEvery == 0 {
+				s.Phase = models.FocusPhaseLongBreak
+			}
+		} else {
+			s.Phase = models.FocusPhaseIdle
+		}
+		s.PhaseStartedAt = end
+		s.PhaseElapsed = 0
+	}
+	s.RemainingSeconds = focusRemaining(s, now)
+	return nil
+}
+
+// recordFocusSegment 记录从当前专注阶段开始（或继续）到 end 的专注时间
+func recordFocusSegment(tx *gorm.DB, s *models.FocusSession, end time.Time, completed bool) error {
+	seconds := int64(end.Sub(s.PhaseStartedAt) / time.Second)
+	if seconds <= 0 && !completed {
+		return nil
+	}
+	segment := models.FocusSegment{
+		SessionID: s.ID,
+		TaskID:    s.TaskID,
+		UserID:    s.UserID,
+		StartedAt: s.PhaseStartedAt,
+		EndedAt:   end,
+		Seconds:   seconds,
+		Completed: completed,
+	}
+	if err := models.CreateFocusSegmentTx(tx, &segment); err != nil {
+		return fmt.Errorf("记录专注时间失败:%w", err)
+	}
+	s.FocusSeconds += seconds
+	return nil
+}
+
+// focusRemaining 计算当前阶段剩余的秒数，等待或已结束时为 0
+func focusRemaining(s *models.FocusSession, now time.Time) int64 {
+	length := s.PhaseLength()
+	if length == 0 {
+		return 0
+	}
+	elapsed := time.Duration(s.PhaseElapsed) * time.Second
+	if s.PausedAt == nil {
+		elapsed += now.Sub(s.PhaseStartedAt)
+	}
+	if elapsed >= length {
+		return 0
+	}
+	return int64((length - elapsed + time.Second - 1) / time.Second)
+}
+
+// newFocusSession 根据请求创建会话设置，未填写的项使用默认值
+func newFocusSession(req dto.FocusStartRequest) (*models.FocusSession, error) {
+	session := &models.FocusSession{
+		WorkMinutes:      orDefault(req.WorkMinutes, defaultWorkMinutes),
+		BreakMinutes:     orDefault(req.BreakMinutes, defaultBreakMinutes),
+		LongBreakMinutes: orDefault(req.LongBreakMinutes, defaultLongBreakMinutes),
+		LongBreakEvery:   orDefault(req.LongBreakEvery, defaultLongBreakEvery),
+	}
+	switch {
+	case session.WorkMinutes < 1 || session.WorkMinutes > 180:
+		return nil, fmt.Errorf("%w:work_minutes必须在1到180之间", ErrInvalidFocusSettings)
+	case session.BreakMinutes < 1 || session.BreakMinutes > 60:
+		return nil, fmt.Errorf("%w:break_minutes必须在1到60之间", ErrInvalidFocusSettings)
+	case session.LongBreakMinutes < 1 || session.LongBreakMinutes > 120:
+		return nil, fmt.Errorf("%w:long_break_minutes必须在1到120之间", ErrInvalidFocusSettings)
+	case session.LongBreakEvery < 1 || session.LongBreakEvery > 12:
+		return nil, fmt.Errorf("%w:long_break_every必须在1到12之间", ErrInvalidFocusSettings)
+	}
+	return session, nil
+}
+
+// orDefault value 为 0 时返回默认值
+func orDefault(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"AITodo/globals"
+	"AITodo/util"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// startJob 启动后台定时任务，每隔 interval 在协程池中执行一次 run
+// 多个实例同时运行时通过 Redis 锁保证同一时刻只有一个实例在执行
+func startJob(name string, interval time.Duration, run func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := globals.TaskPool.Submit(func() {
+				runLocked(name, interval, run)
+			})
+			if err != nil {
+				logrus.Errorf("后台任务 %s 提交失败: %v", name, err)
+			}
+		}
+	}()
+}
+
+// runLocked 持有 Redis 锁执行 run，执行期间每隔 lockTTL/3 续期一次，
+// 执行时间超过 lockTTL 时锁也不会过期，避免多个实例同时执行
+func runLocked(name string, lockTTL time.Duration, run func()) {
+	key := "job:" + name
+	token, ok, err := util.AcquireLock(key, lockTTL)
+	if err != nil {
+		logrus.Errorf("后台任务 %s 获取锁失败: %v", name, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		if err := util.ReleaseLock(key, token); err != nil {
+			logrus.Errorf("后台任务 %s 释放锁失败: %v", name, err)
+		}
+	}()
+	go renewLock(name, key, token, lockTTL, done)
+	run()
+}
+
+// renewLock 在 done 关闭前定期为锁续期
+func renewLock(name, key, token string, lockTTL time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ok, err := util.RenewLock(key, token, lockTTL)
+			if err != nil {
+				logrus.Errorf("后台任务 %s 续期锁失败: %v", name, err)
+			} else if !ok {
+				logrus.Warnf("后台任务 %s 的锁已失效", name)
+				return
+			}
+		}
+	}
+}
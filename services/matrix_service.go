@@ -0,0 +1,59 @@
+package services
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"fmt"
+	"time"
+)
+
+// DefaultUrgentWithin 截止时间在该时长之内（包括已逾期）的任务视为紧急
+const DefaultUrgentWithin = 48 * time.Hour
+
+// GetEisenhowerMatrix 将未完成的任务划分到四象限
+func GetEisenhowerMatrix(userID uint, now time.Time, urgentWithin time.Duration) (*dto.EisenhowerMatrix, error) {
+	tasks, err := models.ListOpenTasks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+
+	matrix := &dto.EisenhowerMatrix{
+		DoFirst:   []models.Task{},
+		Schedule:  []models.Task{},
+		Delegate:  []models.Task{},
+		Eliminate: []models.Task{},
+	}
+	for _, task := range tasks {
+		important, urgent := isImportant(&task), isUrgent(&task, now, urgentWithin)
+		switch {
+		case important && urgent:
+			matrix.DoFirst = append(matrix.DoFirst, task)
+		case important:
+			matrix.Schedule = append(matrix.Schedule, task)
+		case urgent:
+			matrix.Delegate = append(matrix.Delegate, task)
+		default:
+			matrix.Eliminate = append(matrix.Eliminate, task)
+		}
+	}
+	return matrix, nil
+}
+
+// isImportant 用户明确标记时以标记为准，否则 P0、P1 视为重要
+func isImportant(task *models.Task) bool {
+	if task.Important != nil {
+		return *task.Important
+	}
+	return task.Priority == models.PriorityP0 || task.Priority == models.PriorityP1
+}
+
+// isUrgent 用户明确标记时以标记为准，否则 P0 或截止时间临近的任务视为紧急
+func isUrgent(task *models.Task, now time.Time, within time.Duration) bool {
+	if task.Urgent != nil {
+		return *task.Urgent
+	}
+	if task.Priority == models.PriorityP0 {
+		return true
+	}
+	return !task.DueDate.IsZero() && task.DueDate.Before(now.Add(within))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const overdueSweepBatchSize = 200
+
+// ErrInvalidOverduePolicy 不支持的逾期处理策略
+var ErrInvalidOverduePolicy = errors.New("无效的逾期处理策略")
+
+// StartOverdueSweeper 启动后台逾期任务扫描，每隔 interval 执行一次
+func StartOverdueSweeper(interval time.Duration) {
+	startJob("overdue_sweep", interval, func() {
+		count, err := SweepOverdueTasks(time.Now())
+		if err != nil {
+			logrus.Errorf("逾期任务扫描失败: %v", err)
+			return
+		}
+		if count > 0 {
+			logrus.Infof("逾期任务扫描完成，共处理 %d 个任务", count)
+		}
+	})
+}
+
+// SweepOverdueTasks 按各用户的逾期策略处理截止时间早于 now 的未完成任务，返回处理的任务数
+// 单个任务处理失败（例如与用户的修改并发冲突）不影响其他任务，留待下一次扫描
+func SweepOverdueTasks(now time.Time) (int, error) {
+	var count int
+	var afterID uint
+	for {
+		tasks, err := models.ListOverdueTasks(now, afterID, overdueSweepBatchSize)
+		if err != nil {
+			return count, fmt.Errorf("获取逾期任务失败:%w", err)
+		}
+		for i := range tasks {
+			afterID = tasks[i].ID
+			if err := handleOverdueTask(&tasks[i].Task, tasks[i].OverduePolicy, now); err != nil {
+				logrus.WithField("task_id", tasks[i].ID).Warnf("逾期任务处理失败: %v", err)
+				continue
+			}
+			count++
+		}
+		if len(tasks) < overdueSweepBatchSize {
+			return count, nil
+		}
+	}
+}
+
+// handleOverdueTask 按策略处理单个逾期任务，并在同一事务中记录处理结果
+func handleOverdueTask(task *models.Task, policy string, now time.Time) error {
+	record := &models.TaskOverdueRecord{
+		TaskID:      task.ID,
+		UserID:      task.UserID,
+		Policy:      policy,
+		FromStatus:  task.Status,
+		FromDueDate: task.DueDate,
+		SweptAt:     now,
+	}
+
+	before := *task
+	var columns []string
+	switch policy {
+	case models.OverduePolicyFail:
+		task.Status = models.TaskStatusFailed
+		columns = []string{"status"}
+	case models.OverduePolicyRollover:
+		rolloverTask(task, now)
+		columns = []string{"start_date", "due_date"}
+	default:
+		return nil
+	}
+	record.ToStatus = task.Status
+	record.ToDueDate = task.DueDate
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTaskTx(tx, task, &before, columns, models.SystemActor("overdue_sweep"), now); err != nil {
+			return err
+		}
+		return models.CreateOverdueRecord(tx, record)
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"task_id": task.ID,
+		"user_id": task.UserID,
+		"policy":  policy,
+		"status":  task.Status,
+		"due":     task.DueDate,
+	}).Info("逾期任务已处理")
+	return nil
+}
+
+// rolloverTask 将任务顺延到今天，保留原截止时刻和任务时长
+// 若今天的该时刻也已过去，则顺延到今天结束
+func rolloverTask(task *models.Task, now time.Time) {
+	due := task.DueDate.In(now.Location())
+	year, month, day := now.Date()
+	next := time.Date(year, month, day, due.Hour(), due.Minute(), due.Second(), 0, now.Location())
+	if next.Before(now) {
+		next = time.Date(year, month, day, 23, 59, 59, 0, now.Location())
+	}
+
+	shift := next.Sub(task.DueDate)
+	if !task.StartDate.IsZero() {
+		task.StartDate = task.StartDate.Add(shift)
+	}
+	task.DueDate = next
+}
+
+// GetOverduePolicy 获取用户的逾期处理策略，未设置时为保持不变
+func GetOverduePolicy(userID uint) (string, error) {
+	user, err := models.GetUserByID(userID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户失败:%w", err)
+	}
+	if user.OverduePolicy == "" {
+		return models.OverduePolicyLeave, nil
+	}
+	return user.OverduePolicy, nil
+}
+
+// SetOverduePolicy 修改用户的逾期处理策略
+func SetOverduePolicy(userID uint, policy string) error {
+	switch policy {
+	case models.OverduePolicyFail, models.OverduePolicyRollover, models.OverduePolicyLeave:
+	default:
+		return fmt.Errorf("%w:%s", ErrInvalidOverduePolicy, policy)
+	}
+	if err := models.UpdateOverduePolicy(userID, policy); err != nil {
+		return fmt.Errorf("修改逾期处理策略失败:%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidProject 项目字段不合法
+	ErrInvalidProject = errors.New("项目数据不合法")
+	// ErrProjectExists 同名项目已存在
+	ErrProjectExists = errors.New("同名项目已存在")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// ListProjects 获取当前用户的项目
+func ListProjects(userID uint, includeArchived bool) ([]models.Project, error) {
+	projects, err := models.ListProjects(userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目列表失败:%w", err)
+	}
+	return projects, nil
+}
+
+// CreateProject 创建项目
+func CreateProject(userID uint, req dto.CreateProjectRequest) (*models.Project, error) {
+	project := &models.Project{
+		UserID: userID,
+		Name:   strings.TrimSpace(req.Name),
+		Color:  req.Color,
+		Icon:   req.Icon,
+	}
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	if err := checkProjectName(project); err != nil {
+		return nil, err
+	}
+	if err := models.CreateProjectTx(db.DB, project); err != nil {
+		return nil, fmt.Errorf("创建项目失败:%w", err)
+	}
+	return project, nil
+}
+
+// UpdateProject 部分更新项目，归档或取消归档也通过该接口完成
+func UpdateProject(userID, id uint, patch dto.ProjectPatch) (*models.Project, error) {
+	project, err := models.GetProjectByIdTx(db.DB, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目失败:%w", err)
+	}
+
+	var columns []string
+	if patch.Name != nil {
+		project.Name = strings.TrimSpace(*patch.Name)
+		columns = append(columns, "name")
+	}
+	if patch.Color != nil {
+		project.Color = *patch.Color
+		columns = append(columns, "color")
+	}
+	if patch.Icon != nil {
+		project.Icon = *patch.Icon
+		columns = append(columns, "icon")
+	}
+	if patch.Archived != nil {
+		project.Archived = *patch.Archived
+		columns = append(columns, "archived")
+	}
+	if len(columns) == 0 {
+		return project, nil
+	}
+
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	if err := checkProjectName(project); err != nil {
+		return nil, err
+	}
+	if err := project.UpdateColumns(columns...); err != nil {
+		return nil, fmt.Errorf("更新项目失败:%w", err)
+	}
+	return project, nil
+}
+
+// ReorderProjects 调整项目的显示顺序
+func ReorderProjects(userID uint, ids []uint) error {
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w:项目ID重复", ErrInvalidProject)
+		}
+		seen[id] = true
+	}
+	if err := models.ReorderProjects(userID, ids); err != nil {
+		return fmt.Errorf("调整项目顺序失败:%w", err)
+	}
+	return nil
+}
+
+// DeleteProject 删除项目，项目中的任务（包括回收站中的任务）保留并移出项目，每个任务记录一条修改
+func DeleteProject(userID, id uint, opts WriteOptions) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.DeleteProjectTx(tx, userID, id); err != nil {
+			return err
+		}
+		tasks, err := models.ListProjectTasksTx(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		for i := range tasks {
+			before := tasks[i]
+			tasks[i].ProjectID = nil
+			if err := tasks[i].UpdateColumnsTx(tx.Unscoped(), "project_id"); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, opts.Actor, models.AuditUpdate, &before, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("删除项目失败:%w", err)
+	}
+	return nil
+}
+
+// resolveProjectTx 在指定事务中按名称查找项目，不存在时自动创建，供按名称指定项目的批量创建和 AI 助手使用
+func resolveProjectTx(tx *gorm.DB, userID uint, name string) (*models.Project, error) {
+	name = strings.TrimSpace(name)
+	project, err := models.GetProjectByNameTx(tx, userID, name)
+	if err == nil {
+		if project.Archived {
+			return nil, fmt.Errorf("%w:项目 %s 已归档", ErrInvalidTask, name)
+		}
+		return project, nil
+	}
+	if !errors.Is(err, models.ErrProjectNotFound) {
+		return nil, err
+	}
+
+	project = &models.Project{UserID: userID, Name: name}
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	if project, err = models.FindOrCreateProjectTx(tx, project); err != nil {
+		return nil, fmt.Errorf("创建项目失败:%w", err)
+	}
+	if project.Archived {
+		return nil, fmt.Errorf("%w:项目 %s 已归档", ErrInvalidTask, name)
+	}
+	return project, nil
+}
+
+// checkProjectName 检查名称是否与用户的其他项目重复
+func checkProjectName(project *models.Project) error {
+	existing, err := models.GetProjectByNameTx(db.DB, project.UserID, project.Name)
+	if err == nil && existing.ID != project.ID {
+		return ErrProjectExists
+	}
+	if err != nil && !errors.Is(err, models.ErrProjectNotFound) {
+		return fmt.Errorf("获取项目失败:%w", err)
+	}
+	return nil
+}
+
+// validateTaskProject 校验任务所属的项目存在且未归档
+func validateTaskProject(tx *gorm.DB, userID, projectID uint) error {
+	project, err := models.GetProjectByIdTx(tx, userID, projectID)
+	if err != nil {
+		return fmt.Errorf("%w:项目不存在", ErrInvalidTask)
+	}
+	if project.Archived {
+		return fmt.Errorf("%w:项目已归档", ErrInvalidTask)
+	}
+	return nil
+}
+
+func validateProject(project *models.Project) error {
+	if project.Name == "" {
+		return fmt.Errorf("%w:name不能为空", ErrInvalidProject)
+	}
+	if len(project.Name) > 100 {
+		return fmt.Errorf("%w:name长度不能超过100", ErrInvalidProject)
+	}
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		return fmt.Errorf("%w:color格式应为#RRGGBB", ErrInvalidProject)
+	}
+	if len(project.Icon) > 50 {
+		return fmt.Errorf("%w:icon长度不能超过50", ErrInvalidProject)
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+)
+
+// 编辑或删除重复任务单次发生时的影响范围
+const (
+	OccurrenceScopeThis      = "this"      // 仅这一次
+	OccurrenceScopeFollowing = "following" // 这一次及之后
+	OccurrenceScopeAll       = "all"       // 全部
+)
+
+const (
+	MaxOccurrencesPerSeries = 1000 // 单个重复任务在一个时间窗口内最多展开的次数
+	MaxOccurrenceWindowDays = 366  // 展开时间窗口的最大天数
+
+	// maxRecurrenceIterations 展开单个重复任务时最多迭代的次数，包括窗口之前被跳过的发生
+	// 迭代总是从 start_date 开始，该上限避免很早开始的重复任务在每次请求时迭代过多次
+	maxRecurrenceIterations = 100000
+)
+
+var (
+	// ErrNotRecurring 任务没有设置重复规则
+	ErrNotRecurring = errors.New("任务不是重复任务")
+	// ErrOccurrenceNotFound 给定时间不是重复任务的一次发生
+	ErrOccurrenceNotFound = errors.New("该时间不是任务的发生时间")
+)
+
+const recurrenceDateLayout = "20060102T150405Z"
+
+// NormalizeRecurrence 校验并规范化重复规则
+// 支持多行的 RRULE/RDATE/EXDATE，单独的 FREQ=... 视为 RRULE，DTSTART 由任务的 start_date 决定
+func NormalizeRecurrence(raw string) (string, error) {
+	lines := splitRecurrenceLines(raw)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.ToUpper(line), "DTSTART") {
+			return "", fmt.Errorf("重复规则不能包含DTSTART，开始时间以start_date为准")
+		}
+	}
+
+	set, err := rrule.StrSliceToRRuleSetInLoc(lines, time.Local)
+	if err != nil {
+		return "", fmt.Errorf("重复规则解析失败:%v", err)
+	}
+	if set.GetRRule() == nil && len(set.GetRDate()) == 0 {
+		return "", fmt.Errorf("重复规则至少需要包含RRULE或RDATE")
+	}
+	if err := checkRecurrenceFrequency(set); err != nil {
+		return "", err
+	}
+	return formatRecurrence(set), nil
+}
+
+// ExpandOccurrences 展开当前用户在 [start, end] 内的全部任务发生，包括非重复任务
+func ExpandOccurrences(userID uint, start, end time.Time) ([]dto.TaskOccurrence, error) {
+	if err := validateOccurrenceWindow(start, end); err != nil {
+		return nil, err
+	}
+
+	singles, err := models.ListSingleTasksBetween(userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	occurrences := make([]dto.TaskOccurrence, 0, len(singles))
+	for _, task := range singles {
+		occ := dto.TaskOccurrence{Task: task, OccurrenceAt: task.StartDate}
+		if task.SeriesID != nil && task.RecurrenceID != nil {
+			occ.OccurrenceAt = *task.RecurrenceID
+			occ.IsRecurring = true
+		}
+		occurrences = append(occurrences, occ)
+	}
+
+	series, err := models.ListRecurringTasks(userID, end)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	for i := range series {
+		expanded, err := expandSeries(&series[i], start, end)
+		if err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, expanded...)
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].StartDate.Before(occurrences[j].StartDate)
+	})
+	return occurrences, nil
+}
+
+// GetSeriesOccurrences 展开单个重复任务在 [start, end] 内的发生
+func GetSeriesOccurrences(userID, id uint, start, end time.Time) ([]dto.TaskOccurrence, error) {
+	if err := validateOccurrenceWindow(start, end); err != nil {
+		return nil, err
+	}
+	series, err := getRecurringTask(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return expandSeries(series, start, end)
+}
+
+// UpdateOccurrence 按范围编辑重复任务的一次发生
+func UpdateOccurrence(userID, id uint, at time.Time, scope string, patch dto.TaskPatch, opts WriteOptions) (*models.Task, error) {
+	series, err := getRecurringTask(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope == OccurrenceScopeThis || scope == OccurrenceScopeFollowing {
+		// 拆分会修改原系列的重复规则，与修改全部一样需要校验客户端期望的版本号
+		if opts.Version != 0 && series.Version != opts.Version {
+			return nil, models.ErrVersionConflict
+		}
+		// 单次发生仍受原系列的前置任务阻塞，与完成普通任务一样需要前置任务均已完成
+		if err := checkOccurrenceCompletion(series, patch, opts.Force); err != nil {
+			return nil, err
+		}
+	}
+
+	switch scope {
+	case OccurrenceScopeAll, "":
+		return PatchTask(userID, id, patch, opts)
+	case OccurrenceScopeThis:
+		return detachOccurrence(series, at, patch, opts)
+	case OccurrenceScopeFollowing:
+		return splitSeries(series, at, patch, opts)
+	default:
+		return nil, fmt.Errorf("%w:不支持的范围 %s", ErrInvalidTaskQuery, scope)
+	}
+}
+
+// checkOccurrenceCompletion 将单次或之后的发生标记为完成时，校验原系列的前置任务是否均已完成
+func checkOccurrenceCompletion(series *models.Task, patch dto.TaskPatch, force bool) error {
+	if patch.Status == nil {
+		return nil
+	}
+	target := *series
+	target.Status = *patch.Status
+	return checkCompletion(&target, series.Status, force)
+}
+
+// CompleteOccurrence 完成重复任务的一次发生，该次发生会被拆分为已完成的例外实例
+func CompleteOccurrence(userID, id uint, at time.Time, opts WriteOptions) (*models.Task, error) {
+	status := models.TaskStatusCompleted
+	return UpdateOccurrence(userID, id, at, OccurrenceScopeThis, dto.TaskPatch{Status: &status}, opts)
+}
+
+// DeleteOccurrence 按范围删除重复任务的一次发生
+func DeleteOccurrence(userID, id uint, at time.Time, scope string, opts WriteOptions) error {
+	series, err := getRecurringTask(userID, id)
+	if err != nil {
+		return err
+	}
+	if scope == OccurrenceScopeAll || scope == "" {
+		return DeleteTask(userID, id, opts)
+	}
+	if scope != OccurrenceScopeThis && scope != OccurrenceScopeFollowing {
+		return fmt.Errorf("%w:不支持的范围 %s", ErrInvalidTaskQuery, scope)
+	}
+
+	set, err := parseRecurrence(series)
+	if err != nil {
+		return err
+	}
+	at = at.Truncate(time.Second)
+	if !isOccurrence(set, at) {
+		return ErrOccurrenceNotFound
+	}
+
+	before := *series
+	if scope == OccurrenceScopeThis {
+		set.ExDate(at)
+		series.Recurrence = formatRecurrence(set)
+		if err := saveTask(series, &before, []string{"recurrence"}, opts.Actor); err != nil {
+			return fmt.Errorf("删除任务失败:%w", err)
+		}
+		return nil
+	}
+
+	if !at.After(series.StartDate) {
+		return DeleteTask(userID, id, opts)
+	}
+	head, _ := splitRecurrence(set, at)
+	series.Recurrence = formatRecurrence(head)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.TruncateSeriesTx(tx, series, at); err != nil {
+			return err
+		}
+		return recordAudit(tx, opts.Actor, models.AuditUpdate, &before, series)
+	})
+	if err != nil {
+		return fmt.Errorf("删除任务失败:%w", err)
+	}
+	return nil
+}
+
+// detachOccurrence 将一次发生拆分为例外实例并应用修改，原系列通过 EXDATE 排除该次发生
+func detachOccurrence(series *models.Task, at time.Time, patch dto.TaskPatch, opts WriteOptions) (*models.Task, error) {
+	set, err := parseRecurrence(series)
+	if err != nil {
+		return nil, err
+	}
+	at = at.Truncate(time.Second)
+	if !isOccurrence(set, at) {
+		return nil, ErrOccurrenceNotFound
+	}
+
+	exception, err := newOccurrenceTask(series, at, patch)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Recurrence == nil {
+		exception.Recurrence = ""
+	}
+	exception.SeriesID = &series.ID
+	exception.RecurrenceID = &at
+
+	before := *series
+	set.ExDate(at)
+	series.Recurrence = formatRecurrence(set)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTaskTx(tx, series, &before, []string{"recurrence"}, opts.Actor, time.Now()); err != nil {
+			return err
+		}
+		// 例外实例按创建任务的规则校验，并重新分配看板位置和检查项
+		return createTaskTx(tx, &exception, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新任务失败:%w", err)
+	}
+	return &exception, nil
+}
+
+// splitSeries 从某次发生处将重复任务拆分为两个系列，并将修改应用到后一个系列
+func splitSeries(series *models.Task, at time.Time, patch dto.TaskPatch, opts WriteOptions) (*models.Task, error) {
+	set, err := parseRecurrence(series)
+	if err != nil {
+		return nil, err
+	}
+	at = at.Truncate(time.Second)
+	if !isOccurrence(set, at) {
+		return nil, ErrOccurrenceNotFound
+	}
+	// 从第一次发生开始拆分等价于修改全部
+	if !at.After(series.StartDate) {
+		return PatchTask(series.UserID, series.ID, patch, opts)
+	}
+
+	head, tail := splitRecurrence(set, at)
+	next, err := newOccurrenceTask(series, at, patch)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Recurrence == nil {
+		next.Recurrence = formatRecurrence(tail)
+	}
+
+	before := *series
+	series.Recurrence = formatRecurrence(head)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTaskTx(tx, series, &before, []string{"recurrence"}, opts.Actor, time.Now()); err != nil {
+			return err
+		}
+		// 新系列按创建任务的规则校验，并重新分配看板位置和检查项
+		if err := createTaskTx(tx, &next, opts); err != nil {
+			return err
+		}
+		return models.MoveExceptionsTx(tx, series, next.ID, at)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新任务失败:%w", err)
+	}
+	return &next, nil
+}
+
+// newOccurrenceTask 以系列为模板构造从 at 开始的新任务并应用修改
+// 状态须能从系列的状态流转而来，检查项全部重置为未完成
+func newOccurrenceTask(series *models.Task, at time.Time, patch dto.TaskPatch) (models.Task, error) {
+	task := cloneTask(series)
+	task.StartDate = at
+	task.DueDate = at.Add(taskDuration(series))
+	applyTaskPatch(&task, patch)
+	if patch.Tags != nil {
+		task.Tags = nil
+		for _, name := range *patch.Tags {
+			task.Tags = append(task.Tags, models.Tag{Name: name})
+		}
+	}
+	if task.Status != series.Status && !models.CanTransition(series.Status, task.Status) {
+		return models.Task{}, fmt.Errorf("%w:%s → %s", ErrInvalidTransition, series.Status, task.Status)
+	}
+	return task, nil
+}
+
+// splitRecurrence 将重复规则在 at 处拆分，前半部分以 UNTIL 截止，后半部分从 at 开始
+// 使用 COUNT 的规则会按已发生的次数扣减
+func splitRecurrence(set *rrule.Set, at time.Time) (head, tail *rrule.Set) {
+	head, tail = &rrule.Set{}, &rrule.Set{}
+
+	if rule := set.GetRRule(); rule != nil {
+		headOpt, tailOpt := rule.OrigOptions, rule.OrigOptions
+		headOpt.Count = 0
+		headOpt.Until = at.Add(-time.Second)
+		if r, err := rrule.NewRRule(headOpt); err == nil {
+			head.RRule(r)
+		}
+
+		tailOpt.Dtstart = at
+		if rule.OrigOptions.Count > 0 {
+			tailOpt.Count = rule.OrigOptions.Count - len(rule.Between(rule.GetDTStart(), at.Add(-time.Second), true))
+		}
+		if rule.OrigOptions.Count == 0 || tailOpt.Count > 0 {
+			if r, err := rrule.NewRRule(tailOpt); err == nil {
+				tail.RRule(r)
+			}
+		}
+	}
+
+	for _, d := range set.GetRDate() {
+		if d.Before(at) {
+			head.RDate(d)
+		} else {
+			tail.RDate(d)
+		}
+	}
+	for _, d := range set.GetExDate() {
+		if d.Before(at) {
+			head.ExDate(d)
+		} else {
+			tail.ExDate(d)
+		}
+	}
+	return head, tail
+}
+
+// expandSeries 展开重复任务在 [start, end] 内与窗口有交集的发生
+func expandSeries(series *models.Task, start, end time.Time) ([]dto.TaskOccurrence, error) {
+	set, err := parseRecurrence(series)
+	if err != nil {
+		return nil, err
+	}
+	duration := taskDuration(series)
+
+	var occurrences []dto.TaskOccurrence
+	next := set.Iterator()
+	iterations := 0
+	for at, ok := next(); ok && !at.After(end); at, ok = next() {
+		if iterations++; iterations > maxRecurrenceIterations {
+			break
+		}
+		if at.Add(duration).Before(start) {
+			continue
+		}
+		occ := dto.TaskOccurrence{Task: *series, OccurrenceAt: at, IsRecurring: true}
+		occ.StartDate = at
+		occ.DueDate = at.Add(duration)
+		occurrences = append(occurrences, occ)
+		if len(occurrences) >= MaxOccurrencesPerSeries {
+			break
+		}
+	}
+	return occurrences, nil
+}
+
+func getRecurringTask(userID, id uint) (*models.Task, error) {
+	task, err := models.GetTaskById(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	if task.Recurrence == "" {
+		return nil, ErrNotRecurring
+	}
+	return task, nil
+}
+
+// parseRecurrence 解析任务的重复规则，以 start_date 作为 DTSTART
+func parseRecurrence(task *models.Task) (*rrule.Set, error) {
+	set, err := rrule.StrSliceToRRuleSetInLoc(splitRecurrenceLines(task.Recurrence), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w:重复规则解析失败:%v", ErrInvalidTask, err)
+	}
+	if err := checkRecurrenceFrequency(set); err != nil {
+		return nil, fmt.Errorf("%w:%v", ErrInvalidTask, err)
+	}
+	set.DTStart(task.StartDate)
+	return set, nil
+}
+
+// checkRecurrenceFrequency 拒绝按小时、分钟或秒重复的规则，任务的重复间隔至少为一天
+func checkRecurrenceFrequency(set *rrule.Set) error {
+	if rule := set.GetRRule(); rule != nil && rule.OrigOptions.Freq > rrule.DAILY {
+		return fmt.Errorf("不支持按%s重复，重复间隔至少为一天", rule.OrigOptions.Freq)
+	}
+	return nil
+}
+
+// formatRecurrence 将重复规则序列化为不含 DTSTART 的多行文本，日期统一使用 UTC
+func formatRecurrence(set *rrule.Set) string {
+	var lines []string
+	if rule := set.GetRRule(); rule != nil {
+		lines = append(lines, "RRULE:"+rule.OrigOptions.RRuleString())
+	}
+	if dates := set.GetRDate(); len(dates) > 0 {
+		lines = append(lines, "RDATE:"+formatRecurrenceDates(dates))
+	}
+	if dates := set.GetExDate(); len(dates) > 0 {
+		lines = append(lines, "EXDATE:"+formatRecurrenceDates(dates))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatRecurrenceDates(dates []time.Time) string {
+	values := make([]string, len(dates))
+	for i, d := range dates {
+		values[i] = d.UTC().Format(recurrenceDateLayout)
+	}
+	return strings.Join(values, ",")
+}
+
+func splitRecurrenceLines(raw string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "FREQ=") {
+			line = "RRULE:" + line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isOccurrence(set *rrule.Set, at time.Time) bool {
+	return set.After(at, true).Equal(at)
+}
+
+// taskDuration 任务工期，截止时间早于开始时间时视为0
+func taskDuration(task *models.Task) time.Duration {
+	if task.DueDate.Before(task.StartDate) {
+		return 0
+	}
+	return task.DueDate.Sub(task.StartDate)
+}
+
+// cloneTask 复制任务内容用于创建新任务，清空ID、版本号、时间戳和看板位置
+// 检查项只复制内容，新任务的检查项均未完成
+func cloneTask(task *models.Task) models.Task {
+	clone := *task
+	clone.ID = 0
+	clone.Version = 0
+	clone.Rank = ""
+	clone.CreatedAt = time.Time{}
+	clone.UpdatedAt = time.Time{}
+	clone.SeriesID = nil
+	clone.RecurrenceID = nil
+	clone.Checklist = make([]models.ChecklistItem, len(task.Checklist))
+	for i, item := range task.Checklist {
+		clone.Checklist[i] = models.ChecklistItem{Content: item.Content}
+	}
+	return clone
+}
+
+func validateOccurrenceWindow(start, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("%w:end不能早于start", ErrInvalidTaskQuery)
+	}
+	if end.Sub(start) > MaxOccurrenceWindowDays*24*time.Hour {
+		return fmt.Errorf("%w:时间窗口不能超过%d天", ErrInvalidTaskQuery, MaxOccurrenceWindowDays)
+	}
+	return nil
+}
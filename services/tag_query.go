@@ -0,0 +1,149 @@
+package services
+
+import (
+	"AITodo/models"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseTagQuery 解析标签布尔查询，例如 tag:urgent AND NOT (tag:waiting OR tag:someday)
+// 支持 AND、OR、NOT（不区分大小写）和括号，优先级 NOT > AND > OR，相邻的条件之间省略运算符时按 AND 处理
+// 条件写作 tag:名称，tag: 前缀可以省略
+func ParseTagQuery(raw string) (*models.TagExpr, error) {
+	tokens := tokenizeTagQuery(raw)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &tagQueryParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w:标签查询在 %s 处有多余的内容", ErrInvalidTaskQuery, p.tokens[p.pos])
+	}
+	return &expr, nil
+}
+
+type tagQueryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *tagQueryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *tagQueryParser) parseOr() (models.TagExpr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return first, err
+	}
+	args := []models.TagExpr{first}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return next, err
+		}
+		args = append(args, next)
+	}
+	if len(args) == 1 {
+		return first, nil
+	}
+	return models.TagExpr{Op: models.TagExprOr, Args: args}, nil
+}
+
+func (p *tagQueryParser) parseAnd() (models.TagExpr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return first, err
+	}
+	args := []models.TagExpr{first}
+	for {
+		token := p.peek()
+		if token == "" || token == ")" || strings.EqualFold(token, "OR") {
+			break
+		}
+		if strings.EqualFold(token, "AND") {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return next, err
+		}
+		args = append(args, next)
+	}
+	if len(args) == 1 {
+		return first, nil
+	}
+	return models.TagExpr{Op: models.TagExprAnd, Args: args}, nil
+}
+
+func (p *tagQueryParser) parseUnary() (models.TagExpr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return models.TagExpr{}, fmt.Errorf("%w:标签查询不完整", ErrInvalidTaskQuery)
+	case strings.EqualFold(token, "NOT"):
+		p.pos++
+		arg, err := p.parseUnary()
+		if err != nil {
+			return arg, err
+		}
+		return models.TagExpr{Op: models.TagExprNot, Args: []models.TagExpr{arg}}, nil
+	case token == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return expr, err
+		}
+		if p.peek() != ")" {
+			return expr, fmt.Errorf("%w:标签查询缺少右括号", ErrInvalidTaskQuery)
+		}
+		p.pos++
+		return expr, nil
+	case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR"):
+		return models.TagExpr{}, fmt.Errorf("%w:标签查询在 %s 处缺少条件", ErrInvalidTaskQuery, token)
+	}
+
+	p.pos++
+	name := token
+	if len(name) > len("tag:") && strings.EqualFold(name[:len("tag:")], "tag:") {
+		name = name[len("tag:"):]
+	}
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return models.TagExpr{}, fmt.Errorf("%w:%v", ErrInvalidTaskQuery, err)
+	}
+	return models.TagExpr{Op: models.TagExprTag, Tag: name}, nil
+}
+
+// tokenizeTagQuery 按空白和括号切分查询
+func tokenizeTagQuery(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range raw {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxTaskTags 单个任务最多的标签数量
+const MaxTaskTags = 20
+
+// ErrInvalidTag 标签名称不合法
+var ErrInvalidTag = errors.New("标签数据不合法")
+
+// ErrTagExists 同名标签已存在，需要合并时使用合并接口
+var ErrTagExists = errors.New("标签已存在")
+
+// ListTags 获取当前用户的标签及使用次数
+func ListTags(userID uint) ([]models.TagCount, error) {
+	tags, err := models.ListTags(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签失败:%w", err)
+	}
+	return tags, nil
+}
+
+// CreateTag 创建标签
+func CreateTag(userID uint, name string) (*models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := models.GetTagByName(userID, name); err == nil {
+		return nil, ErrTagExists
+	}
+	tag := &models.Tag{UserID: userID, Name: name}
+	if err := models.CreateTag(tag); err != nil {
+		return nil, fmt.Errorf("创建标签失败:%w", err)
+	}
+	return tag, nil
+}
+
+// RenameTag 重命名标签，新名称已被其他标签使用时返回 ErrTagExists
+func RenameTag(userID, id uint, name string) (*models.Tag, error) {
+	tag, err := models.GetTagById(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签失败:%w", err)
+	}
+	name, err = normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == tag.Name {
+		return tag, nil
+	}
+	if existing, err := models.GetTagByName(userID, name); err == nil && existing.ID != tag.ID {
+		return nil, ErrTagExists
+	}
+
+	tag.Name = name
+	if err := models.RenameTag(tag); err != nil {
+		return nil, fmt.Errorf("重命名标签失败:%w", err)
+	}
+	return tag, nil
+}
+
+// MergeTag 将标签 id 合并到标签 intoID，返回合并后保留的标签
+func MergeTag(userID, id, intoID uint) (*models.Tag, error) {
+	if id == intoID {
+		return nil, fmt.Errorf("%w:不能合并到自身", ErrInvalidTag)
+	}
+	source, err := models.GetTagById(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签失败:%w", err)
+	}
+	target, err := models.GetTagById(userID, intoID)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签失败:%w", err)
+	}
+	if err := models.MergeTags(source, target); err != nil {
+		return nil, fmt.Errorf("合并标签失败:%w", err)
+	}
+	return target, nil
+}
+
+// DeleteTag 删除标签，并从所有任务上移除
+func DeleteTag(userID, id uint) error {
+	tag, err := models.GetTagById(userID, id)
+	if err != nil {
+		return fmt.Errorf("获取标签失败:%w", err)
+	}
+	if err := models.DeleteTag(tag); err != nil {
+		return fmt.Errorf("删除标签失败:%w", err)
+	}
+	return nil
+}
+
+// setTaskTagsTx 在指定事务中将任务的标签替换为 names，不存在的标签自动创建
+// 标签没有变化时返回 false
+func setTaskTagsTx(tx *gorm.DB, task *models.Task, names []string) (bool, error) {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return false, err
+	}
+	if sameTagNames(task.Tags, names) {
+		return false, nil
+	}
+	tags, err := models.FindOrCreateTagsTx(tx, task.UserID, names)
+	if err != nil {
+		return false, fmt.Errorf("保存标签失败:%w", err)
+	}
+	if err := models.SetTaskTagsTx(tx, task.ID, tags); err != nil {
+		return false, fmt.Errorf("保存标签失败:%w", err)
+	}
+	task.Tags = tags
+	return true, nil
+}
+
+// tagNames 提取标签名称
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+func sameTagNames(tags []models.Tag, names []string) bool {
+	if len(tags) != len(names) {
+		return false
+	}
+	current := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		current[tag.Name] = true
+	}
+	for _, name := range names {
+		if !current[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTagNames 规范化标签名称并去重，忽略空名称
+func normalizeTagNames(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		if strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#")) == "" {
+			continue
+		}
+		name, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	if len(result) > MaxTaskTags {
+		return nil, fmt.Errorf("%w:单个任务最多 %d 个标签", ErrInvalidTask, MaxTaskTags)
+	}
+	return result, nil
+}
+
+// normalizeTagName 去掉首尾空白和开头的 #，名称不能为空且不能包含空白和括号
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", fmt.Errorf("%w:标签名称不能为空", ErrInvalidTag)
+	}
+	if len(name) > 50 {
+		return "", fmt.Errorf("%w:标签名称长度不能超过50", ErrInvalidTag)
+	}
+	if strings.ContainsAny(name, " \t\r\n()\"") {
+		return "", fmt.Errorf("%w:标签名称不能包含空白、括号或引号", ErrInvalidTag)
+	}
+	return name, nil
+}
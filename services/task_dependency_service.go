@@ -0,0 +1,266 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDependencyCycle 添加依赖后会形成环
+	ErrDependencyCycle = errors.New("添加该依赖会形成循环依赖")
+	// ErrTaskBlocked 任务仍有未完成的前置任务
+	ErrTaskBlocked = errors.New("任务仍有未完成的前置任务")
+)
+
+// AddDependency 添加前置任务，taskID 在 blockedByID 完成之前不能完成
+// 环检测和写入在同一事务中进行，并锁定用户记录，避免并发添加的依赖共同形成环
+func AddDependency(userID, taskID, blockedByID uint) (*models.TaskDependency, error) {
+	if taskID == blockedByID {
+		return nil, ErrDependencyCycle
+	}
+	var dep *models.TaskDependency
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockUserTx(tx, userID); err != nil {
+			return fmt.Errorf("获取依赖关系失败:%w", err)
+		}
+		tasks, err := models.ListTasksByIDsTx(tx, userID, []uint{taskID, blockedByID})
+		if err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		if len(tasks) != 2 {
+			return models.ErrTaskNotFound
+		}
+
+		deps, err := models.ListDependenciesTx(tx, userID)
+		if err != nil {
+			return fmt.Errorf("获取依赖关系失败:%w", err)
+		}
+		blockers := make(map[uint][]uint)
+		for i, d := range deps {
+			if d.TaskID == taskID && d.BlockedByID == blockedByID {
+				dep = &deps[i]
+				return nil
+			}
+			blockers[d.TaskID] = append(blockers[d.TaskID], d.BlockedByID)
+		}
+		// 若 blockedByID 已经（间接）被 taskID 阻塞，再添加这条边就会成环
+		if reachable(blockers, blockedByID, taskID) {
+			return ErrDependencyCycle
+		}
+
+		dep = &models.TaskDependency{UserID: userID, TaskID: taskID, BlockedByID: blockedByID}
+		if err := dep.CreateTx(tx); err != nil {
+			return fmt.Errorf("添加依赖失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+// RemoveDependency 删除前置任务
+func RemoveDependency(userID, taskID, blockedByID uint) error {
+	if err := models.DeleteDependency(userID, taskID, blockedByID); err != nil {
+		return fmt.Errorf("删除依赖失败:%w", err)
+	}
+	return nil
+}
+
+// GetDependencies 获取任务的前置任务和后续任务
+func GetDependencies(userID, taskID uint) (*dto.TaskDependencies, error) {
+	if _, err := models.GetTaskById(userID, taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	blockedBy, err := models.ListBlockers(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取依赖关系失败:%w", err)
+	}
+	blocking, err := models.ListDependents(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取依赖关系失败:%w", err)
+	}
+	return &dto.TaskDependencies{BlockedBy: blockedBy, Blocking: blocking}, nil
+}
+
+// GetActionableTasks 获取当前可以开始的任务：未完成且所有前置任务均已完成
+func GetActionableTasks(userID uint) ([]models.Task, error) {
+	tasks, err := models.ListActionableTasks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	return tasks, nil
+}
+
+// GetCriticalPath 计算依赖图的最早开始/完成时间和关键路径
+// 任务工期取 due_date - start_date，最早开始时间为自身 start_date 与所有前置任务最早完成时间中的较大者
+// targetID 不为 0 时计算到该任务为止的关键路径，否则取最早完成时间最晚的任务
+func GetCriticalPath(userID, targetID uint) (*dto.CriticalPathResponse, error) {
+	deps, err := models.ListDependencies(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取依赖关系失败:%w", err)
+	}
+
+	blockers := make(map[uint][]uint)
+	idSet := make(map[uint]bool)
+	for _, d := range deps {
+		blockers[d.TaskID] = append(blockers[d.TaskID], d.BlockedByID)
+		idSet[d.TaskID], idSet[d.BlockedByID] = true, true
+	}
+	if targetID != 0 {
+		idSet[targetID] = true
+	}
+	ids := make([]uint, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+
+	tasks, err := models.ListTasksByIDs(userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	byID := make(map[uint]models.Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	if targetID != 0 {
+		if _, ok := byID[targetID]; !ok {
+			return nil, models.ErrTaskNotFound
+		}
+	}
+
+	order, err := topologicalOrder(byID, blockers)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make(map[uint]*dto.ScheduleItem, len(order))
+	critical := make(map[uint]uint) // 决定该任务最早开始时间的前置任务
+	for _, id := range order {
+		task := byID[id]
+		item := &dto.ScheduleItem{TaskID: id, Title: task.Title, EarliestStart: task.StartDate}
+		for _, b := range blockers[id] {
+			prev, ok := schedule[b]
+			if !ok {
+				continue
+			}
+			if prev.EarliestFinish.After(item.EarliestStart) {
+				item.EarliestStart = prev.EarliestFinish
+				critical[id] = b
+			}
+		}
+		item.EarliestFinish = item.EarliestStart.Add(taskDuration(&task))
+		item.Late = !task.DueDate.IsZero() && item.EarliestFinish.After(task.DueDate)
+		schedule[id] = item
+	}
+
+	resp := &dto.CriticalPathResponse{Path: []dto.ScheduleItem{}, Schedule: make([]dto.ScheduleItem, 0, len(order))}
+	end := targetID
+	for _, id := range order {
+		resp.Schedule = append(resp.Schedule, *schedule[id])
+		if targetID == 0 && (end == 0 || schedule[id].EarliestFinish.After(schedule[end].EarliestFinish)) {
+			end = id
+		}
+	}
+	if end == 0 {
+		return resp, nil
+	}
+
+	resp.Finish = schedule[end].EarliestFinish
+	for id, ok := end, true; ok; id, ok = critical[id] {
+		resp.Path = append([]dto.ScheduleItem{*schedule[id]}, resp.Path...)
+	}
+	return resp, nil
+}
+
+// checkCompletion 任务从未完成变为完成时，校验前置任务是否均已完成
+func checkCompletion(task *models.Task, previousStatus string, force bool) error {
+	if force || task.Status != models.TaskStatusCompleted || previousStatus == models.TaskStatusCompleted {
+		return nil
+	}
+	open, err := models.CountOpenBlockers(task.UserID, task.ID)
+	if err != nil {
+		return fmt.Errorf("获取依赖关系失败:%w", err)
+	}
+	if open > 0 {
+		return fmt.Errorf("%w（%d个），如需强制完成请设置force", ErrTaskBlocked, open)
+	}
+	return nil
+}
+
+// reachable 判断沿 edges 从 from 出发能否到达 to
+func reachable(edges map[uint][]uint, from, to uint) bool {
+	visited := map[uint]bool{}
+	stack := []uint{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, edges[id]...)
+	}
+	return false
+}
+
+// topologicalOrder 返回前置任务在前的拓扑序，同一层按开始时间排序
+func topologicalOrder(tasks map[uint]models.Task, blockers map[uint][]uint) ([]uint, error) {
+	indegree := make(map[uint]int, len(tasks))
+	dependents := make(map[uint][]uint)
+	for id := range tasks {
+		indegree[id] = 0
+	}
+	for id := range tasks {
+		for _, b := range blockers[id] {
+			if _, ok := tasks[b]; !ok {
+				continue
+			}
+			indegree[id]++
+			dependents[b] = append(dependents[b], id)
+		}
+	}
+
+	byStart := func(ids []uint) {
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := tasks[ids[i]], tasks[ids[j]]
+			if !a.StartDate.Equal(b.StartDate) {
+				return a.StartDate.Before(b.StartDate)
+			}
+			return a.ID < b.ID
+		})
+	}
+
+	var ready []uint
+	for id, n := range indegree {
+		if n == 0 {
+			ready = append(ready, id)
+		}
+	}
+	order := make([]uint, 0, len(tasks))
+	for len(ready) > 0 {
+		byStart(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, d := range dependents[id] {
+			if indegree[d]--; indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(order) != len(tasks) {
+		return nil, ErrDependencyCycle
+	}
+	return order, nil
+}
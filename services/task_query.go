@@ -0,0 +1,241 @@
+package services
+
+import (
+	"AITodo/dto"
+	"AITodo/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTaskPageSize = 20
+	MaxTaskPageSize     = 100
+)
+
+// ErrInvalidTaskQuery 查询参数不合法
+var ErrInvalidTaskQuery = errors.New("无效的查询参数")
+
+// ListTasks 分页查询当前用户的任务
+func ListTasks(userID uint, q dto.TaskQuery) (*dto.TaskListResponse, error) {
+	filter, err := buildTaskFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	sorts, err := parseTaskSorts(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var after []interface{}
+	if q.Cursor != "" {
+		after, err = decodeTaskCursor(q.Cursor, sorts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultTaskPageSize
+	}
+	if limit > MaxTaskPageSize {
+		limit = MaxTaskPageSize
+	}
+
+	total, err := models.CountTasks(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("无法获取任务列表:%w", err)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	tasks, err := models.ListTasks(userID, filter, sorts, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("无法获取任务列表:%w", err)
+	}
+
+	resp := &dto.TaskListResponse{Data: tasks, Total: total}
+	if len(tasks) > limit {
+		resp.Data = tasks[:limit]
+		resp.NextCursor = encodeTaskCursor(resp.Data[limit-1], sorts)
+	}
+	return resp, nil
+}
+
+func buildTaskFilter(q dto.TaskQuery) (models.TaskFilter, error) {
+	filter := models.TaskFilter{
+		Statuses:   splitList(q.Status),
+		Priorities: splitList(q.Priority),
+		Categories: splitList(q.Category),
+		Location:   strings.TrimSpace(q.Location),
+		Overdue:    q.Overdue,
+		Keyword:    strings.TrimSpace(q.Q),
+	}
+
+	for _, item := range splitList(q.Project) {
+		if item == "none" {
+			filter.NoProject = true
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 32)
+		if err != nil || id == 0 {
+			return filter, fmt.Errorf("%w:无效的项目ID %s", ErrInvalidTaskQuery, item)
+		}
+		filter.ProjectIDs = append(filter.ProjectIDs, uint(id))
+	}
+
+	var err error
+	if filter.Tags, err = ParseTagQuery(q.Tags); err != nil {
+		return filter, err
+	}
+	if filter.StartFrom, err = ParseQueryTime("start_from", q.StartFrom, false); err != nil {
+		return filter, err
+	}
+	if filter.StartTo, err = ParseQueryTime("start_to", q.StartTo, true); err != nil {
+		return filter, err
+	}
+	if filter.DueFrom, err = ParseQueryTime("due_from", q.DueFrom, false); err != nil {
+		return filter, err
+	}
+	if filter.DueTo, err = ParseQueryTime("due_to", q.DueTo, true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseTaskSorts 解析排序参数，并追加 id 作为唯一的排序字段保证游标稳定
+func parseTaskSorts(raw string) ([]models.TaskSort, error) {
+	var sorts []models.TaskSort
+	seen := map[string]bool{}
+	for _, item := range splitList(raw) {
+		s := models.TaskSort{Field: item}
+		if strings.HasPrefix(item, "-") {
+			s = models.TaskSort{Field: item[1:], Desc: true}
+		}
+		if _, ok := models.TaskSortFields[s.Field]; !ok {
+			return nil, fmt.Errorf("%w:不支持的排序字段 %s", ErrInvalidTaskQuery, s.Field)
+		}
+		if seen[s.Field] {
+			continue
+		}
+		seen[s.Field] = true
+		sorts = append(sorts, s)
+	}
+
+	if len(sorts) == 0 {
+		sorts = []models.TaskSort{{Field: "due_date"}}
+	}
+	if !seen["id"] {
+		sorts = append(sorts, models.TaskSort{Field: "id"})
+	}
+	return sorts, nil
+}
+
+// encodeTaskCursor 将最后一条记录在各排序字段上的取值编码为游标
+func encodeTaskCursor(task models.Task, sorts []models.TaskSort) string {
+	values := make([]string, len(sorts))
+	for i, s := range sorts {
+		values[i] = taskSortValue(task, s.Field)
+	}
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(cursor string, sorts []models.TaskSort) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w:cursor", ErrInvalidTaskQuery)
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != len(sorts) {
+		return nil, fmt.Errorf("%w:cursor 与排序参数不匹配", ErrInvalidTaskQuery)
+	}
+
+	after := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		switch {
+		case s.Field == "id":
+			id, err := strconv.ParseUint(values[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w:cursor", ErrInvalidTaskQuery)
+			}
+			after[i] = id
+		case models.TaskSortFields[s.Field]:
+			t, err := time.Parse(time.RFC3339Nano, values[i])
+			if err != nil {
+				return nil, fmt.Errorf("%w:cursor", ErrInvalidTaskQuery)
+			}
+			after[i] = t
+		default:
+			after[i] = values[i]
+		}
+	}
+	return after, nil
+}
+
+func taskSortValue(task models.Task, field string) string {
+	switch field {
+	case "id":
+		return strconv.FormatUint(uint64(task.ID), 10)
+	case "title":
+		return task.Title
+	case "status":
+		return task.Status
+	case "priority":
+		return task.Priority
+	case "category":
+		return task.Category
+	case "location":
+		return task.Location
+	case "start_date":
+		return task.StartDate.Format(time.RFC3339Nano)
+	case "due_date":
+		return task.DueDate.Format(time.RFC3339Nano)
+	case "created_at":
+		return task.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return task.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// ParseQueryTime 解析查询参数中的时间，支持 2006-01-02、2006-01-02 15:04:05 和 RFC3339 三种格式
+// 仅给出日期的上限按当天 23:59:59 处理
+func ParseQueryTime(name, value string, upper bool) (*time.Time, error) {
+	return ParseQueryTimeIn(name, value, upper, time.Local)
+}
+
+// ParseQueryTimeIn 与 ParseQueryTime 相同，没有时区的时间按 loc 时区解析
+func ParseQueryTimeIn(name, value string, upper bool, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		if upper {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	return nil, fmt.Errorf("%w:%s 时间格式错误", ErrInvalidTaskQuery, name)
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,53 +1,422 @@
 package services
 
 import (
+	"AITodo/db"
+	"AITodo/dto"
 	"AITodo/models"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-// GetAllTasks 获取所有任务
-func GetAllTasks() (*[]models.Task, error) {
-	tasks, err := models.GetAllTasks()
+// ErrInvalidTask 任务字段不合法
+var ErrInvalidTask = errors.New("任务数据不合法")
+
+// WriteOptions 任务写操作的附加选项
+type WriteOptions struct {
+	Version uint              // 客户端期望的版本号（If-Match），为 0 表示不校验，但写入时仍会检测并发修改
+	Force   bool              // 允许完成仍有未完成前置任务的任务
+	Actor   models.AuditActor // 发起修改的操作者，记录到审计日志
+}
+
+// GetAllTasks 获取当前用户的所有任务
+func GetAllTasks(userID uint) (*[]models.Task, error) {
+	tasks, err := models.GetAllTasks(userID)
 	if err != nil {
 		return &[]models.Task{}, fmt.Errorf("无法获取任务列表:%w", err)
 	}
 	return tasks, nil
 }
 
+// GetTask 获取当前用户的单个任务
+func GetTask(userID, id uint) (*models.Task, error) {
+	task, err := models.GetTaskById(userID, id)
+	if err != nil {
+		return &models.Task{}, fmt.Errorf("获取任务失败:%w", err)
+	}
+	return task, nil
+}
+
 // CreateTask 创建新任务
-func CreateTask(task models.Task) error {
-	if err := task.Create(); err != nil {
+func CreateTask(task models.Task, opts WriteOptions) error {
+	// 例外实例只能通过编辑重复任务的单次发生产生
+	task.SeriesID, task.RecurrenceID = nil, nil
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return createTaskTx(tx, &task, opts)
+	})
+}
+
+// createTaskTx 在指定事务中校验并创建任务，同时记录状态流转和审计日志
+// 任务的 SeriesID 和 RecurrenceID 原样保留，由调用方保证只有拆分重复任务时才会设置
+func createTaskTx(tx *gorm.DB, task *models.Task, opts WriteOptions) error {
+	task.ID = 0
+	task.StartedAt, task.CompletedAt = nil, nil
+	if task.Status == "" {
+		task.Status = models.TaskStatusPending
+	}
+	if task.Category == "" {
+		task.Category = models.DefaultCategoryName
+	}
+	if task.Priority == "" {
+		task.Priority = models.DefaultPriority
+	}
+	if err := validateTask(task); err != nil {
+		return err
+	}
+	if err := validateTaskCategory(tx, task.UserID, task.Category); err != nil {
+		return err
+	}
+	if task.ParentID != nil {
+		if err := validateParent(tx, task.UserID, *task.ParentID); err != nil {
+			return err
+		}
+	}
+	if task.ProjectID != nil {
+		if err := validateTaskProject(tx, task.UserID, *task.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	names, err := normalizeTagNames(tagNames(task.Tags))
+	if err != nil {
+		return err
+	}
+	if task.Checklist, err = normalizeChecklist(task.Checklist); err != nil {
+		return err
+	}
+	if task.Tags, err = models.FindOrCreateTagsTx(tx, task.UserID, names); err != nil {
+		return fmt.Errorf("创建任务失败:%w", err)
+	}
+
+	// 新任务排在看板中所在列的末尾
+	last, err := models.LastRankTx(tx, task.UserID, task.Status)
+	if err != nil {
+		return fmt.Errorf("创建任务失败:%w", err)
+	}
+	if task.Rank, err = models.RankBetween(last, ""); err != nil {
+		return fmt.Errorf("创建任务失败:%w", err)
+	}
+
+	now := time.Now()
+	if err := applyStatusTransition(task, "", now); err != nil {
+		return err
+	}
+	if err := task.CreateTx(tx); err != nil {
+		return fmt.Errorf("创建任务失败:%w", err)
+	}
+	if err := recordStatusChange(tx, task, "", now); err != nil {
+		return fmt.Errorf("创建任务失败:%w", err)
+	}
+	if err := recordAudit(tx, opts.Actor, models.AuditCreate, nil, task); err != nil {
 		return fmt.Errorf("创建任务失败:%w", err)
 	}
 	return nil
 }
 
-// UpdateTask 更新任务
-func UpdateTask(id uint, req models.Task) (*models.Task, error) {
-	task, err := models.GetTaskById(id)
+// UpdateTask 更新任务，仅允许更新属于 userID 的任务
+// req.Tags 为 nil 时保留原有标签，否则替换为给出的标签
+func UpdateTask(userID, id uint, req models.Task, opts WriteOptions) (*models.Task, error) {
+	var task *models.Task
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = updateTaskTx(tx, userID, id, req, opts)
+		return err
+	})
 	if err != nil {
-		return &models.Task{}, fmt.Errorf("获取任务失败:%w", err)
+		return &models.Task{}, err
 	}
+	return task, nil
+}
+
+// updateTaskTx 在指定事务中更新任务的全部字段
+func updateTaskTx(tx *gorm.DB, userID, id uint, req models.Task, opts WriteOptions) (*models.Task, error) {
+	task, err := getTaskForWrite(tx, userID, id, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+	before := *task
 
 	task.Title = req.Title
 	task.Category = req.Category
 	task.Location = req.Location
 	task.Description = req.Description
-	task.Status = req.Status
+	if req.Status != "" {
+		task.Status = req.Status
+	}
+	if req.Priority != "" {
+		task.Priority = req.Priority
+	}
+	task.Important = req.Important
+	task.Urgent = req.Urgent
 	task.StartDate = req.StartDate
 	task.DueDate = req.DueDate
+	task.Recurrence = req.Recurrence
+	task.ProjectID = req.ProjectID
+	task.EstimateMinutes = req.EstimateMinutes
 
-	if err := task.Update(); err != nil {
-		return &models.Task{}, fmt.Errorf("更新任务失败:%w", err)
+	if err := validateTask(task); err != nil {
+		return nil, err
 	}
+	if err := checkCategoryChange(tx, task, &before); err != nil {
+		return nil, err
+	}
+	if err := checkProjectChange(tx, task, &before); err != nil {
+		return nil, err
+	}
+	if err := checkCompletion(task, before.Status, opts.Force); err != nil {
+		return nil, err
+	}
+	if req.Tags != nil {
+		if _, err := setTaskTagsTx(tx, task, tagNames(req.Tags)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := saveTaskTx(tx, task, &before, nil, opts.Actor, time.Now()); err != nil {
+		return nil, fmt.Errorf("更新任务失败:%w", err)
+	}
+	return task, nil
+}
 
+// PatchTask 部分更新任务，只修改 patch 中出现的字段
+func PatchTask(userID, id uint, patch dto.TaskPatch, opts WriteOptions) (*models.Task, error) {
+	var task *models.Task
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = patchTaskTx(tx, userID, id, patch, opts)
+		return err
+	})
+	if err != nil {
+		return &models.Task{}, err
+	}
 	return task, nil
 }
 
-// DeleteTask 删除任务
-func DeleteTask(id uint) error {
-	if err := models.DeleteTask(id); err != nil {
-		return fmt.Errorf("删除任务失败:%w", err)
+// patchTaskTx 在指定事务中部分更新任务
+func patchTaskTx(tx *gorm.DB, userID, id uint, patch dto.TaskPatch, opts WriteOptions) (*models.Task, error) {
+	task, err := getTaskForWrite(tx, userID, id, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+	before := *task
+
+	columns := applyTaskPatch(task, patch)
+	if len(columns) == 0 && patch.Tags == nil {
+		return task, nil
+	}
+
+	if err := validateTask(task); err != nil {
+		return nil, err
+	}
+	if err := checkCategoryChange(tx, task, &before); err != nil {
+		return nil, err
+	}
+	if err := checkProjectChange(tx, task, &before); err != nil {
+		return nil, err
+	}
+	if err := checkCompletion(task, before.Status, opts.Force); err != nil {
+		return nil, err
+	}
+	if patch.Tags != nil {
+		changed, err := setTaskTagsTx(tx, task, *patch.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if !changed && len(columns) == 0 {
+			return task, nil
+		}
+		// 只修改标签时也需要递增版本号
+		if columns == nil {
+			columns = []string{}
+		}
+	}
+
+	if err := saveTaskTx(tx, task, &before, columns, opts.Actor, time.Now()); err != nil {
+		return nil, fmt.Errorf("更新任务失败:%w", err)
+	}
+	return task, nil
+}
+
+// saveTask 在同一事务中保存任务修改，并记录状态流转和审计日志
+func saveTask(task, before *models.Task, columns []string, actor models.AuditActor) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return saveTaskTx(tx, task, before, columns, actor, time.Now())
+	})
+}
+
+// saveTaskTx 在指定事务中保存任务修改，before 为修改前的任务
+// columns 为空时保存全部字段，否则只保存指定列（状态变化时自动附带 started_at/completed_at）
+// 状态变化且未指定新的排序值时，任务排到看板中新状态列的末尾
+func saveTaskTx(tx *gorm.DB, task, before *models.Task, columns []string, actor models.AuditActor, now time.Time) error {
+	if err := applyStatusTransition(task, before.Status, now); err != nil {
+		return err
+	}
+	if task.Status != before.Status {
+		if task.Rank == before.Rank {
+			last, err := models.LastRankTx(tx, task.UserID, task.Status)
+			if err != nil {
+				return err
+			}
+			if task.Rank, err = models.RankBetween(last, ""); err != nil {
+				return err
+			}
+			if columns != nil {
+				columns = append(columns, "board_rank")
+			}
+		}
+		if columns != nil {
+			columns = append(columns, "started_at", "completed_at")
+		}
+	}
+
+	var err error
+	if columns == nil {
+		err = task.UpdateTx(tx)
+	} else {
+		err = task.UpdateColumnsTx(tx, columns...)
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordStatusChange(tx, task, before.Status, now); err != nil {
+		return err
+	}
+	return recordAudit(tx, actor, models.AuditUpdate, before, task)
+}
+
+// getTaskForWrite 读取待修改的任务并校验客户端期望的版本号
+func getTaskForWrite(tx *gorm.DB, userID, id, version uint) (*models.Task, error) {
+	task, err := models.GetTaskByIdTx(tx, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	if version != 0 && task.Version != version {
+		return nil, models.ErrVersionConflict
+	}
+	return task, nil
+}
+
+// applyTaskPatch 将 patch 应用到任务上，返回被修改的列名
+func applyTaskPatch(task *models.Task, patch dto.TaskPatch) []string {
+	var columns []string
+	if patch.Title != nil {
+		task.Title = *patch.Title
+		columns = append(columns, "title")
+	}
+	if patch.Category != nil {
+		task.Category = *patch.Category
+		columns = append(columns, "category")
+	}
+	if patch.Location != nil {
+		task.Location = *patch.Location
+		columns = append(columns, "location")
+	}
+	if patch.Description != nil {
+		task.Description = *patch.Description
+		columns = append(columns, "description")
+	}
+	if patch.Status != nil {
+		task.Status = *patch.Status
+		columns = append(columns, "status")
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+		columns = append(columns, "priority")
+	}
+	if patch.Important.Set {
+		task.Important = patch.Important.Value
+		columns = append(columns, "important")
+	}
+	if patch.Urgent.Set {
+		task.Urgent = patch.Urgent.Value
+		columns = append(columns, "urgent")
+	}
+	if patch.StartDate != nil {
+		task.StartDate = *patch.StartDate
+		columns = append(columns, "start_date")
+	}
+	if patch.DueDate != nil {
+		task.DueDate = *patch.DueDate
+		columns = append(columns, "due_date")
+	}
+	if patch.Recurrence != nil {
+		task.Recurrence = *patch.Recurrence
+		columns = append(columns, "recurrence")
+	}
+	if patch.ProjectID != nil {
+		task.ProjectID = patch.ProjectID
+		if *patch.ProjectID == 0 {
+			task.ProjectID = nil
+		}
+		columns = append(columns, "project_id")
+	}
+	if patch.EstimateMinutes != nil {
+		task.EstimateMinutes = *patch.EstimateMinutes
+		columns = append(columns, "estimate_minutes")
+	}
+	if patch.Rank != nil {
+		task.Rank = *patch.Rank
+		columns = append(columns, "board_rank")
+	}
+	return columns
+}
+
+// checkCategoryChange 任务的类别变化时校验新类别属于当前用户
+func checkCategoryChange(tx *gorm.DB, task, before *models.Task) error {
+	if task.Category == before.Category {
+		return nil
+	}
+	return validateTaskCategory(tx, task.UserID, task.Category)
+}
+
+// checkProjectChange 任务被移入其他项目时校验目标项目，保持原项目不变时即使项目已归档也允许修改
+func checkProjectChange(tx *gorm.DB, task, before *models.Task) error {
+	if task.ProjectID == nil || (before.ProjectID != nil && *before.ProjectID == *task.ProjectID) {
+		return nil
+	}
+	return validateTaskProject(tx, task.UserID, *task.ProjectID)
+}
+
+// validateTask 校验任务整体是否一致
+func validateTask(task *models.Task) error {
+	if strings.TrimSpace(task.Title) == "" {
+		return fmt.Errorf("%w:title不能为空", ErrInvalidTask)
+	}
+	if len(task.Title) > 255 {
+		return fmt.Errorf("%w:title长度不能超过255", ErrInvalidTask)
+	}
+	if !task.StartDate.IsZero() && !task.DueDate.IsZero() && task.StartDate.After(task.DueDate) {
+		return fmt.Errorf("%w:start_date不能晚于due_date", ErrInvalidTask)
+	}
+	if task.EstimateMinutes < 0 || task.EstimateMinutes > models.MaxEstimateMinutes {
+		return fmt.Errorf("%w:estimate_minutes必须在0到%d之间", ErrInvalidTask, models.MaxEstimateMinutes)
+	}
+	if !models.IsValidTaskStatus(task.Status) {
+		return fmt.Errorf("%w:无效的状态 %s", ErrInvalidTask, task.Status)
+	}
+	if !models.IsValidPriority(task.Priority) {
+		return fmt.Errorf("%w:无效的优先级 %s", ErrInvalidTask, task.Priority)
+	}
+	if task.Recurrence != "" {
+		if task.SeriesID != nil {
+			return fmt.Errorf("%w:重复任务的单次发生不能再设置重复规则", ErrInvalidTask)
+		}
+		if task.StartDate.IsZero() {
+			return fmt.Errorf("%w:重复任务必须设置start_date", ErrInvalidTask)
+		}
+		recurrence, err := NormalizeRecurrence(task.Recurrence)
+		if err != nil {
+			return fmt.Errorf("%w:%v", ErrInvalidTask, err)
+		}
+		task.Recurrence = recurrence
 	}
 	return nil
 }
+
+// DeleteTask 删除任务，仅允许删除属于 userID 的任务，子任务一并删除
+func DeleteTask(userID, id uint, opts WriteOptions) error {
+	return DeleteTaskWithChildren(userID, id, ChildrenCascade, opts)
+}
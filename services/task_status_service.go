@@ -0,0 +1,62 @@
+package services
+
+import (
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTransition 不允许的状态流转
+var ErrInvalidTransition = errors.New("不允许的状态变更")
+
+// GetStatusHistory 获取任务的状态流转记录
+func GetStatusHistory(userID, id uint) ([]models.TaskStatusHistory, error) {
+	if _, err := models.GetTaskById(userID, id); err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	histories, err := models.ListStatusHistory(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取状态记录失败:%w", err)
+	}
+	return histories, nil
+}
+
+// applyStatusTransition 校验状态流转并维护 started_at/completed_at，from 为空表示新建任务
+func applyStatusTransition(task *models.Task, from string, now time.Time) error {
+	if task.Status == from {
+		return nil
+	}
+	if from != "" && !models.CanTransition(from, task.Status) {
+		return fmt.Errorf("%w:%s → %s", ErrInvalidTransition, from, task.Status)
+	}
+
+	switch task.Status {
+	case models.TaskStatusInProgress:
+		if task.StartedAt == nil {
+			task.StartedAt = &now
+		}
+		task.CompletedAt = nil
+	case models.TaskStatusCompleted:
+		task.CompletedAt = &now
+	default:
+		task.CompletedAt = nil
+	}
+	return nil
+}
+
+// recordStatusChange 状态发生变化时在同一事务中写入流转记录
+func recordStatusChange(tx *gorm.DB, task *models.Task, from string, now time.Time) error {
+	if task.Status == from {
+		return nil
+	}
+	return models.CreateStatusHistory(tx, &models.TaskStatusHistory{
+		TaskID:     task.ID,
+		UserID:     task.UserID,
+		FromStatus: from,
+		ToStatus:   task.Status,
+		ChangedAt:  now,
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"AITodo/config"
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const trashPurgeBatchSize = 500
+
+// ListTrash 获取回收站中的任务及其自动清理时间
+func ListTrash(userID uint) ([]dto.TrashedTask, error) {
+	tasks, err := models.ListTrashedTasks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取回收站失败:%w", err)
+	}
+	items := make([]dto.TrashedTask, len(tasks))
+	for i, t := range tasks {
+		items[i] = dto.TrashedTask{Task: t, PurgeAt: t.DeletedAt.Time.Add(config.Cfg.Job.TrashRetention)}
+	}
+	return items, nil
+}
+
+// RestoreTask 将任务移出回收站，同一次删除的子任务和例外实例一并恢复
+func RestoreTask(userID, id uint, opts WriteOptions) (*models.Task, error) {
+	task, err := models.GetTrashedTask(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+
+	var restored *models.Task
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.RestoreTaskTx(tx, task); err != nil {
+			return err
+		}
+		if restored, err = models.GetTaskByIdTx(tx, userID, id); err != nil {
+			return err
+		}
+		return recordAudit(tx, opts.Actor, models.AuditRestore, task, restored)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("恢复任务失败:%w", err)
+	}
+	return restored, nil
+}
+
+// PurgeTask 彻底删除回收站中的任务
+func PurgeTask(userID, id uint) error {
+	task, err := models.GetTrashedTask(userID, id)
+	if err != nil {
+		return fmt.Errorf("获取任务失败:%w", err)
+	}
+	keys, err := models.PurgeTask(task)
+	if err != nil {
+		return fmt.Errorf("删除任务失败:%w", err)
+	}
+	deleteStoredFiles(keys)
+	return nil
+}
+
+// PurgeExpiredTrash 彻底删除在回收站中超过保留时长的任务，返回删除的数量
+func PurgeExpiredTrash(now time.Time, retention time.Duration) (int, error) {
+	cutoff := now.Add(-retention)
+	var total int
+	for {
+		n, keys, err := models.PurgeTrashedBefore(cutoff, trashPurgeBatchSize)
+		total += n
+		deleteStoredFiles(keys)
+		if err != nil {
+			return total, fmt.Errorf("清理回收站失败:%w", err)
+		}
+		if n < trashPurgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// StartTrashPurger 启动回收站的后台自动清理
+func StartTrashPurger(interval, retention time.Duration) {
+	startJob("trash_purge", interval, func() {
+		count, err := PurgeExpiredTrash(time.Now(), retention)
+		if err != nil {
+			logrus.Errorf("回收站清理失败: %v", err)
+		}
+		if count > 0 {
+			logrus.Infof("回收站清理完成，共删除 %d 个任务", count)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 删除父任务时子任务的处理方式
+const (
+	ChildrenCascade = "cascade" // 删除整棵子树
+	ChildrenPromote = "promote" // 子任务提升到被删除任务的父任务下
+)
+
+// GetTaskTree 获取任务及其全部子任务，并汇总完成进度
+func GetTaskTree(userID, id uint) (*dto.TaskNode, error) {
+	root, err := models.GetTaskById(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	descendants, err := models.GetSubtree(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取子任务失败:%w", err)
+	}
+
+	nodes := map[uint]*dto.TaskNode{root.ID: {Task: *root, Children: []*dto.TaskNode{}}}
+	for _, task := range descendants {
+		nodes[task.ID] = &dto.TaskNode{Task: task, Children: []*dto.TaskNode{}}
+	}
+	// descendants 按层级顺序返回，父节点总是先于子节点出现
+	for _, task := range descendants {
+		parent := nodes[*task.ParentID]
+		parent.Children = append(parent.Children, nodes[task.ID])
+	}
+
+	rollUpProgress(nodes[root.ID])
+	return nodes[root.ID], nil
+}
+
+// rollUpProgress 自底向上统计叶子任务的完成数
+func rollUpProgress(node *dto.TaskNode) dto.TaskProgress {
+	if len(node.Children) == 0 {
+		node.Progress = dto.TaskProgress{Total: 1}
+		if node.Status == models.TaskStatusCompleted {
+			node.Progress.Done = 1
+		}
+		return node.Progress
+	}
+	node.Progress = dto.TaskProgress{}
+	for _, child := range node.Children {
+		p := rollUpProgress(child)
+		node.Progress.Done += p.Done
+		node.Progress.Total += p.Total
+	}
+	return node.Progress
+}
+
+// MoveTask 将任务及其子树移动到新的父任务下，parentID 为 nil 表示移动到顶层
+// 环检测和写入在同一事务中进行，并锁定用户记录，避免并发的移动共同形成环
+func MoveTask(userID, id uint, parentID *uint, opts WriteOptions) (*models.Task, error) {
+	if parentID != nil && *parentID == id {
+		return nil, fmt.Errorf("%w:不能将任务移动到自身下", ErrInvalidTask)
+	}
+
+	var task *models.Task
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockUserTx(tx, userID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		var err error
+		if task, err = getTaskForWrite(tx, userID, id, opts.Version); err != nil {
+			return err
+		}
+		if parentID != nil {
+			if err := validateParent(tx, userID, *parentID); err != nil {
+				return err
+			}
+			descendants, err := models.GetSubtreeTx(tx, userID, id)
+			if err != nil {
+				return fmt.Errorf("获取子任务失败:%w", err)
+			}
+			for _, d := range descendants {
+				if d.ID == *parentID {
+					return fmt.Errorf("%w:不能将任务移动到自己的子任务下", ErrInvalidTask)
+				}
+			}
+		}
+
+		before := *task
+		task.ParentID = parentID
+		if err := saveTaskTx(tx, task, &before, []string{"parent_id"}, opts.Actor, time.Now()); err != nil {
+			return fmt.Errorf("移动任务失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DeleteTaskWithChildren 将任务移入回收站，mode 决定子任务是一并删除还是提升一级
+func DeleteTaskWithChildren(userID, id uint, mode string, opts WriteOptions) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTaskTx(tx, userID, id, mode, opts)
+	})
+}
+
+// deleteTaskTx 在指定事务中将任务移入回收站并记录审计日志
+func deleteTaskTx(tx *gorm.DB, userID, id uint, mode string, opts WriteOptions) error {
+	if mode != ChildrenCascade && mode != ChildrenPromote && mode != "" {
+		return fmt.Errorf("%w:不支持的子任务处理方式 %s", ErrInvalidTaskQuery, mode)
+	}
+	task, err := models.GetTaskByIdTx(tx, userID, id)
+	if err != nil {
+		return fmt.Errorf("删除任务失败:%w", err)
+	}
+
+	var children []models.Task
+	if mode == ChildrenPromote {
+		if children, err = models.ListChildrenTx(tx, userID, []uint{id}); err == nil {
+			err = models.DeleteTaskPromoteChildrenTx(tx, task)
+		}
+	} else {
+		err = trashTaskTx(tx, task)
+	}
+	var audit *models.TaskAudit
+	if err == nil {
+		audit, err = newAudit(opts.Actor, models.AuditDelete, task, nil)
+	}
+	if err == nil {
+		err = models.CreateAudit(tx, audit)
+	}
+	// 提升的子任务记录为删除连带的修改，撤销删除时一并恢复原来的父任务
+	for i := 0; err == nil && i < len(children); i++ {
+		promoted := children[i]
+		promoted.ParentID = task.ParentID
+		promoted.Version++
+		err = recordCascade(tx, opts.Actor, &children[i], &promoted, audit.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("删除任务失败:%w", err)
+	}
+	return nil
+}
+
+// trashTaskTx 在指定事务中将任务及其整棵子树移入回收站
+func trashTaskTx(tx *gorm.DB, task *models.Task) error {
+	descendants, err := models.GetSubtreeTx(tx, task.UserID, task.ID)
+	if err != nil {
+		return err
+	}
+	ids := []uint{task.ID}
+	for _, d := range descendants {
+		ids = append(ids, d.ID)
+	}
+	return models.DeleteTasksTx(tx, task.UserID, ids)
+}
+
+// validateParent 校验父任务存在且属于当前用户
+func validateParent(tx *gorm.DB, userID, parentID uint) error {
+	if _, err := models.GetTaskByIdTx(tx, userID, parentID); err != nil {
+		return fmt.Errorf("%w:父任务不存在", ErrInvalidTask)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxTemplateItems 单个模板最多包含的任务数量
+const MaxTemplateItems = 50
+
+// 模板相关错误
+var (
+	ErrInvalidTemplate = errors.New("模板数据不合法")
+	ErrTemplateExists  = errors.New("同名模板已存在")
+)
+
+// ListTemplates 获取用户的全部模板
+func ListTemplates(userID uint) ([]models.TaskTemplate, error) {
+	templates, err := models.ListTemplates(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败:%w", err)
+	}
+	return templates, nil
+}
+
+// TemplateNames 获取当前用户全部模板的名称，用于生成 AI 工具的可选值
+func TemplateNames(userID uint) ([]string, error) {
+	templates, err := ListTemplates(userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(templates))
+	for i, t := range templates {
+		names[i] = t.Name
+	}
+	return names, nil
+}
+
+// GetTemplate 获取模板
+func GetTemplate(userID, id uint) (*models.TaskTemplate, error) {
+	template, err := models.GetTemplateByIdTx(db.DB, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败:%w", err)
+	}
+	return template, nil
+}
+
+// CreateTemplate 创建模板
+func CreateTemplate(userID uint, req dto.TemplateRequest) (*models.TaskTemplate, error) {
+	template := &models.TaskTemplate{UserID: userID}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyTemplateRequest(tx, template, req); err != nil {
+			return err
+		}
+		if err := models.CreateTemplateTx(tx, template); err != nil {
+			return fmt.Errorf("创建模板失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateTemplate 修改模板，模板中的任务整体替换
+func UpdateTemplate(userID, id uint, req dto.TemplateRequest) (*models.TaskTemplate, error) {
+	var template *models.TaskTemplate
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if template, err = models.GetTemplateByIdTx(tx, userID, id); err != nil {
+			return fmt.Errorf("获取模板失败:%w", err)
+		}
+		if err := applyTemplateRequest(tx, template, req); err != nil {
+			return err
+		}
+		if err := models.UpdateTemplateTx(tx, template); err != nil {
+			return fmt.Errorf("修改模板失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// DeleteTemplate 删除模板
+func DeleteTemplate(userID, id uint) error {
+	if err := models.DeleteTemplate(userID, id); err != nil {
+		return fmt.Errorf("删除模板失败:%w", err)
+	}
+	return nil
+}
+
+// InstantiateTemplate 以 anchor 为基准实例化模板，模板中的任务在同一事务中逐个按 CreateTask 的流程创建，
+// 任一任务创建失败时全部回滚
+func InstantiateTemplate(userID, id uint, anchor time.Time, opts WriteOptions) ([]*models.Task, error) {
+	template, err := models.GetTemplateByIdTx(db.DB, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败:%w", err)
+	}
+
+	outcomes, committed, err := BulkApply(userID, BulkAtomic, TemplateOperations(template, anchor), opts)
+	if err != nil {
+		return nil, fmt.Errorf("实例化模板失败:%w", err)
+	}
+	tasks := make([]*models.Task, len(outcomes))
+	for i, outcome := range outcomes {
+		if outcome.Err != nil && !errors.Is(outcome.Err, ErrBulkRolledBack) {
+			return nil, fmt.Errorf("实例化模板失败:第 %d 个任务 %s:%w", i+1, template.Items[i].Title, outcome.Err)
+		}
+		tasks[i] = outcome.Task
+	}
+	// 每个任务都成功但事务提交失败时，所有结果都是 ErrBulkRolledBack
+	if !committed {
+		return nil, fmt.Errorf("实例化模板失败:%w", ErrBulkRolledBack)
+	}
+	return tasks, nil
+}
+
+// TemplateOperationsByName 按名称查找模板并生成以 anchor 为基准的创建操作，供 AI 助手实例化模板
+func TemplateOperationsByName(userID uint, name string, anchor time.Time) ([]dto.BulkOperation, error) {
+	template, err := models.GetTemplateByNameTx(db.DB, userID, strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败:%w", err)
+	}
+	return TemplateOperations(template, anchor), nil
+}
+
+// TemplateOperations 将模板中的任务转换为以 anchor 为基准的创建操作
+func TemplateOperations(template *models.TaskTemplate, anchor time.Time) []dto.BulkOperation {
+	ops := make([]dto.BulkOperation, len(template.Items))
+	for i, item := range template.Items {
+		task := &models.Task{
+			Title:       item.Title,
+			Category:    item.Category,
+			Location:    item.Location,
+			Description: item.Description,
+			Priority:    item.Priority,
+			StartDate:   anchor.Add(time.Duration(item.StartOffsetMinutes) * time.Minute),
+			DueDate:     anchor.Add(time.Duration(item.DueOffsetMinutes) * time.Minute),
+		}
+		for _, name := range item.Tags {
+			task.Tags = append(task.Tags, models.Tag{Name: name})
+		}
+		for _, content := range item.Checklist {
+			task.Checklist = append(task.Checklist, models.ChecklistItem{Content: content})
+		}
+		ops[i] = dto.BulkOperation{Op: BulkCreate, Task: task, ProjectName: template.ProjectName}
+	}
+	return ops
+}
+
+// ParseTemplateAnchor 解析实例化锚点，支持日期（当天零点）、日期时间和 RFC 3339 格式，为空时取今天零点
+func ParseTemplateAnchor(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w:anchor 格式应为 2006-01-02", ErrInvalidTemplate)
+}
+
+// applyTemplateRequest 校验请求并写入模板
+func applyTemplateRequest(tx *gorm.DB, template *models.TaskTemplate, req dto.TemplateRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w:模板名称不能为空且长度不能超过100", ErrInvalidTemplate)
+	}
+	existing, err := models.GetTemplateByNameTx(tx, template.UserID, name)
+	if err == nil && existing.ID != template.ID {
+		return ErrTemplateExists
+	}
+	if err != nil && !errors.Is(err, models.ErrTemplateNotFound) {
+		return err
+	}
+	projectName := strings.TrimSpace(req.ProjectName)
+	if len(projectName) > 100 {
+		return fmt.Errorf("%w:项目名称长度不能超过100", ErrInvalidTemplate)
+	}
+	if len(req.Items) == 0 || len(req.Items) > MaxTemplateItems {
+		return fmt.Errorf("%w:模板需要包含 1 到 %d 个任务", ErrInvalidTemplate, MaxTemplateItems)
+	}
+
+	items := make([]models.TaskTemplateItem, len(req.Items))
+	for i, item := range req.Items {
+		if err := normalizeTemplateItem(tx, template.UserID, &item); err != nil {
+			return fmt.Errorf("第 %d 个任务:%w", i+1, err)
+		}
+		items[i] = item
+	}
+	template.Name, template.Description, template.ProjectName = name, req.Description, projectName
+	template.Items = items
+	return nil
+}
+
+// normalizeTemplateItem 校验模板中的任务，规则与创建任务一致，类别为空时使用默认类别
+func normalizeTemplateItem(tx *gorm.DB, userID uint, item *models.TaskTemplateItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" || len(item.Title) > 255 {
+		return fmt.Errorf("%w:任务标题不能为空且长度不能超过255", ErrInvalidTemplate)
+	}
+	if item.Category == "" {
+		item.Category = models.DefaultCategoryName
+	}
+	if err := validateTaskCategory(tx, userID, item.Category); err != nil {
+		return err
+	}
+	if item.Priority != "" && !models.IsValidPriority(item.Priority) {
+		return fmt.Errorf("%w:优先级只能为 P0~P3", ErrInvalidTemplate)
+	}
+	if item.DueOffsetMinutes < item.StartOffsetMinutes {
+		return fmt.Errorf("%w:截止时间不能早于开始时间", ErrInvalidTemplate)
+	}
+	tags, err := normalizeTagNames(item.Tags)
+	if err != nil {
+		return err
+	}
+	item.Tags = tags
+	checklist := make([]models.ChecklistItem, len(item.Checklist))
+	for i, content := range item.Checklist {
+		checklist[i] = models.ChecklistItem{Content: content}
+	}
+	if checklist, err = normalizeChecklist(checklist); err != nil {
+		return err
+	}
+	item.Checklist = make([]string, len(checklist))
+	for i, c := range checklist {
+		item.Checklist[i] = c.Content
+	}
+	return nil
+}
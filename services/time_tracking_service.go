@@ -0,0 +1,181 @@
+package services
+
+import (
+	"AITodo/db"
+	"AITodo/dto"
+	"AITodo/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTimerRunning 用户已有运行中的计时器
+	ErrTimerRunning = errors.New("已有正在运行的计时器")
+	// ErrNoRunningTimer 用户没有运行中的计时器
+	ErrNoRunningTimer = errors.New("没有正在运行的计时器")
+	// ErrInvalidTimeEntry 计时记录不合法
+	ErrInvalidTimeEntry = errors.New("计时记录不合法")
+)
+
+// maxTimeEntryNote 计时备注的最大长度（字符）
+const maxTimeEntryNote = 255
+
+// StartTimer 为任务开始计时，每个用户同一时间只能有一个运行中的计时器
+// 已有运行中的计时器时，req.Switch 为 true 则先停止原计时器，否则返回 ErrTimerRunning
+func StartTimer(userID, taskID uint, req dto.TimerStartRequest) (*dto.TimerStartResult, error) {
+	note, err := normalizeTimeEntryNote(req.Note)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.TimerStartResult{}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		// 锁定用户记录后再检查，并发的开始请求只有一个能通过
+		running, err := models.GetRunningEntryTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取计时器失败:%w", err)
+		}
+		now := time.Now()
+		if running != nil {
+			if !req.Switch {
+				return fmt.Errorf("%w:任务 %d 正在计时", ErrTimerRunning, running.TaskID)
+			}
+			if err := running.StopTx(tx, now); err != nil {
+				return fmt.Errorf("停止计时失败:%w", err)
+			}
+			result.Stopped = running
+		}
+
+		entry := models.TimeEntry{TaskID: taskID, UserID: userID, StartedAt: now, Note: note}
+		if err := models.CreateTimeEntryTx(tx, &entry); err != nil {
+			return fmt.Errorf("开始计时失败:%w", err)
+		}
+		result.Entry = &entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StopTimer 停止用户运行中的计时器
+func StopTimer(userID uint) (*models.TimeEntry, error) {
+	var entry *models.TimeEntry
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = models.GetRunningEntryTx(tx, userID, true)
+		if err != nil {
+			return fmt.Errorf("获取计时器失败:%w", err)
+		}
+		if entry == nil {
+			return ErrNoRunningTimer
+		}
+		if err := entry.StopTx(tx, time.Now()); err != nil {
+			return fmt.Errorf("停止计时失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetRunningTimer 获取用户运行中的计时器，没有时返回 nil
+func GetRunningTimer(userID uint) (*models.TimeEntry, error) {
+	entry, err := models.GetRunningEntryTx(db.DB, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("获取计时器失败:%w", err)
+	}
+	return entry, nil
+}
+
+// ListTimeEntries 获取任务的计时记录及总时长
+func ListTimeEntries(userID, taskID uint) (*dto.TaskTimeEntries, error) {
+	task, err := models.GetTaskById(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败:%w", err)
+	}
+	entries, err := models.ListTimeEntries(userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取计时记录失败:%w", err)
+	}
+
+	result := &dto.TaskTimeEntries{Entries: entries, EstimateMinutes: task.EstimateMinutes}
+	for _, entry := range entries {
+		result.TrackedSeconds += entry.Seconds
+	}
+	return result, nil
+}
+
+// AddTimeEntry 为任务手动补录一段已结束的计时
+func AddTimeEntry(userID, taskID uint, req dto.TimeEntryRequest) (*models.TimeEntry, error) {
+	note, err := normalizeTimeEntryNote(req.Note)
+	if err != nil {
+		return nil, err
+	}
+	if !req.EndedAt.After(req.StartedAt) {
+		return nil, fmt.Errorf("%w:ended_at必须晚于started_at", ErrInvalidTimeEntry)
+	}
+	if req.EndedAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w:ended_at不能晚于当前时间", ErrInvalidTimeEntry)
+	}
+
+	endedAt := req.EndedAt
+	entry := models.TimeEntry{
+		TaskID:    taskID,
+		UserID:    userID,
+		StartedAt: req.StartedAt,
+		EndedAt:   &endedAt,
+		Seconds:   int64(endedAt.Sub(req.StartedAt) / time.Second),
+		Note:      note,
+		Manual:    true,
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		if err := models.CreateTimeEntryTx(tx, &entry); err != nil {
+			return fmt.Errorf("添加计时记录失败:%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteTimeEntry 删除任务的计时记录，删除运行中的计时器相当于放弃本次计时
+func DeleteTimeEntry(userID, taskID, id uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.GetTaskByIdTx(tx, userID, taskID); err != nil {
+			return fmt.Errorf("获取任务失败:%w", err)
+		}
+		entry, err := models.GetTimeEntryTx(tx, userID, taskID, id)
+		if err != nil {
+			return fmt.Errorf("获取计时记录失败:%w", err)
+		}
+		if err := models.DeleteTimeEntryTx(tx, entry); err != nil {
+			return fmt.Errorf("删除计时记录失败:%w", err)
+		}
+		return nil
+	})
+}
+
+// normalizeTimeEntryNote 去除备注首尾空白并校验长度
+func normalizeTimeEntryNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxTimeEntryNote {
+		return "", fmt.Errorf("%w:note长度不能超过%d", ErrInvalidTimeEntry, maxTimeEntryNote)
+	}
+	return note, nil
+}
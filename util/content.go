@@ -17,3 +17,34 @@ func GetUserID(c *gin.Context) (uint, error) {
 	}
 	return uid, nil
 }
+
+// IdempotencyCommit 处理函数上报的写入结果，幂等中间件据此决定释放幂等键还是保存响应
+type IdempotencyCommit struct {
+	Committed bool   // 修改是否已经提交
+	BatchID   string // 已提交修改所属的批次，重试时返回给客户端
+}
+
+// SetIdempotencyCommit 记录本次请求的修改是否已提交，未提交时幂等键会被释放以便重试
+func SetIdempotencyCommit(c *gin.Context, committed bool, batchID string) {
+	c.Set("idempotency_commit", IdempotencyCommit{Committed: committed, BatchID: batchID})
+}
+
+// GetIdempotencyCommit 返回处理函数上报的写入结果，未上报时第二个返回值为 false
+func GetIdempotencyCommit(c *gin.Context) (IdempotencyCommit, bool) {
+	commit, ok := c.Get("idempotency_commit")
+	if !ok {
+		return IdempotencyCommit{}, false
+	}
+	result, ok := commit.(IdempotencyCommit)
+	return result, ok
+}
+
+// MarkResponseIncomplete 标记响应未完整写出（例如流式输出中途出错），幂等中间件不会重放该响应
+func MarkResponseIncomplete(c *gin.Context) {
+	c.Set("response_incomplete", true)
+}
+
+// IsResponseIncomplete 判断响应是否被标记为未完整写出
+func IsResponseIncomplete(c *gin.Context) bool {
+	return c.GetBool("response_incomplete")
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// downloadKey 由 JWT 私钥派生的下载链接签名密钥，服务重启后已签发的链接仍然有效
+func downloadKey() []byte {
+	key, _ := JwtSecret.(*ecdsa.PrivateKey)
+	if key == nil {
+		return nil
+	}
+	return hmacSHA256(key.D.Bytes(), "attachment-download")
+}
+
+func downloadSignature(id uint, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(downloadKey(), fmt.Sprintf("%d:%d", id, expires)))
+}
+
+// SignDownloadURL 生成附件的下载地址，在 expires 之前无需登录即可访问
+func SignDownloadURL(id uint, expires time.Time) string {
+	unix := expires.Unix()
+	return fmt.Sprintf("/attachment/%d/download?expires=%d&sig=%s", id, unix, downloadSignature(id, unix))
+}
+
+// VerifyDownloadURL 校验下载地址的签名和有效期
+func VerifyDownloadURL(id uint, expires, sig string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(downloadSignature(id, unix)))
+}
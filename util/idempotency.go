@@ -0,0 +1,70 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotentResponse 幂等键对应的请求记录，Done 为 false 表示原请求仍在处理中
+type IdempotentResponse struct {
+	Hash        string `json:"hash"` // 请求指纹，用于拒绝以不同请求体复用同一幂等键
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
+
+// ReserveIdempotencyKey 为请求占用幂等键，成功时返回 true
+// 幂等键已被占用时返回已有的记录，由调用方决定重放响应还是拒绝请求
+func ReserveIdempotencyKey(key, hash string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	data, err := json.Marshal(IdempotentResponse{Hash: hash})
+	if err != nil {
+		return nil, false, err
+	}
+	// 已有记录可能在 SETNX 与 GET 之间过期，此时重新尝试占用
+	for i := 0; i < 2; i++ {
+		ok, err := Client.SetNX(ctx, idempotencyKey(key), data, ttl).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return nil, true, nil
+		}
+
+		raw, err := Client.Get(ctx, idempotencyKey(key)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		var existing IdempotentResponse
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return nil, false, err
+		}
+		return &existing, false, nil
+	}
+	return nil, false, errors.New("幂等键占用失败")
+}
+
+// SaveIdempotentResponse 保存请求的最终响应，在 ttl 内供重试请求重放
+func SaveIdempotentResponse(key string, resp IdempotentResponse, ttl time.Duration) error {
+	resp.Done = true
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return Client.Set(ctx, idempotencyKey(key), data, ttl).Err()
+}
+
+// ReleaseIdempotencyKey 释放幂等键，请求失败后允许客户端使用同一幂等键重试
+func ReleaseIdempotencyKey(key string) error {
+	return Client.Del(ctx, idempotencyKey(key)).Err()
+}
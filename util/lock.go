@@ -0,0 +1,44 @@
+package util
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// releaseLockScript 只有持有者才能释放锁，避免误删其他实例在锁过期后重新获取的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// renewLockScript 只有持有者才能延长锁的过期时间
+var renewLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// AcquireLock 尝试获取分布式锁，成功时返回用于释放锁的令牌
+// ttl 应大于持锁期间任务的最长执行时间，锁会在过期后自动释放
+func AcquireLock(key string, ttl time.Duration) (string, bool, error) {
+	token := uuid.NewString()
+	ok, err := Client.SetNX(ctx, "lock:"+key, token, ttl).Result()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// ReleaseLock 释放由 AcquireLock 获取的锁
+func ReleaseLock(key, token string) error {
+	return releaseLockScript.Run(ctx, Client, []string{"lock:" + key}, token).Err()
+}
+
+// RenewLock 将由 AcquireLock 获取的锁的过期时间重新设置为 ttl，锁已不属于 token 时返回 false
+func RenewLock(key, token string, ttl time.Duration) (bool, error) {
+	n, err := renewLockScript.Run(ctx, Client, []string{"lock:" + key}, token, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
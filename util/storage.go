@@ -0,0 +1,95 @@
+package util
+
+import (
+	"AITodo/config"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrObjectNotFound 存储中不存在指定的文件
+var ErrObjectNotFound = errors.New("文件不存在")
+
+// FileStorage 附件的存储后端，key 由调用方生成，使用 / 分隔层级
+type FileStorage interface {
+	// Put 保存 size 字节的内容，key 已存在时覆盖
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Open 打开已保存的内容，调用方负责关闭
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除内容，key 不存在时不报错
+	Delete(key string) error
+}
+
+// Storage 当前使用的附件存储后端
+var Storage FileStorage
+
+// InitStorage 按配置初始化附件存储后端
+func InitStorage(cfg config.StorageConfig) error {
+	switch cfg.Driver {
+	case "local":
+		if err := os.MkdirAll(cfg.LocalDir, 0o750); err != nil {
+			return fmt.Errorf("创建附件目录失败: %w", err)
+		}
+		Storage = &LocalStorage{Dir: cfg.LocalDir}
+	case "s3":
+		if cfg.S3.Endpoint == "" || cfg.S3.Bucket == "" {
+			return errors.New("s3 存储需要配置 endpoint 和 bucket")
+		}
+		Storage = NewS3Storage(cfg.S3)
+	default:
+		return fmt.Errorf("不支持的附件存储方式: %s", cfg.Driver)
+	}
+	return nil
+}
+
+// LocalStorage 将附件保存在本地文件系统的 Dir 目录下
+type LocalStorage struct {
+	Dir string
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// Put 先写入临时文件再重命名，避免读取到写了一半的文件
+func (s *LocalStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("文件大小不一致: 期望 %d 字节，实际 %d 字节", size, written)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}